@@ -0,0 +1,18 @@
+package access
+
+import "github.com/yetiz-org/goth-util/internal/clock"
+
+// Clock is the source of time for schedulers and limiters. Tests substitute a
+// manual clock so they run without sleeping.
+type Clock = clock.Clock
+
+// Timer is the part of *time.Timer used through a Clock.
+type Timer = clock.Timer
+
+// SystemClock is the Clock backed by the time package.
+var SystemClock = clock.System
+
+// clockOrSystem returns c, or SystemClock when c is nil.
+func clockOrSystem(c Clock) Clock {
+	return clock.OrSystem(c)
+}
@@ -0,0 +1,178 @@
+package access
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ReloadableContext is the error- and cancellation-aware form of Reloadable.
+// Reload returns an error when the new state could not be applied, and should
+// stop early and return ctx.Err() when ctx is done.
+type ReloadableContext interface {
+	Reload(ctx context.Context) error
+}
+
+// ReloadableContextFunc adapts an ordinary function to ReloadableContext.
+type ReloadableContextFunc func(ctx context.Context) error
+
+// Reload calls f.
+func (f ReloadableContextFunc) Reload(ctx context.Context) error {
+	return f(ctx)
+}
+
+// AsReloadableContext adapts a Reloadable. The returned Reload fails without
+// calling r when ctx is already done, turns a panic into an error, and
+// reports the error from r's Err() error method when it has one.
+func AsReloadableContext(r Reloadable) ReloadableContext {
+	return ReloadableContextFunc(func(ctx context.Context) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		return reloadComponent(r)
+	})
+}
+
+// ContextReloadable adapts a ReloadableContext to Reloadable. Reload runs
+// with context.Background() and remembers the error, which Err returns; this
+// lets a Manager report failures of the wrapped component.
+type ContextReloadable struct {
+	r ReloadableContext
+
+	mu  sync.Mutex
+	err error
+}
+
+// AsReloadable adapts a ReloadableContext to Reloadable.
+func AsReloadable(r ReloadableContext) *ContextReloadable {
+	return &ContextReloadable{r: r}
+}
+
+// Reload implements Reloadable.
+func (c *ContextReloadable) Reload() {
+	err := c.r.Reload(context.Background())
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+}
+
+// Err returns the error of the most recent Reload.
+func (c *ContextReloadable) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// PreparedReload is new state that has been loaded and validated but not yet
+// applied. Exactly one of Commit or Abort is called.
+type PreparedReload interface {
+	// Commit applies the prepared state. It must not fail; anything that can
+	// go wrong belongs in Prepare.
+	Commit()
+
+	// Abort discards the prepared state, leaving the current state untouched.
+	Abort()
+}
+
+// TwoPhaseReloadable is a component whose reload is split into a validating
+// Prepare step and an infallible commit, so that several components can be
+// reloaded all-or-nothing with ReloadTwoPhase.
+type TwoPhaseReloadable interface {
+	Prepare(ctx context.Context) (PreparedReload, error)
+}
+
+// TwoPhaseFunc adapts an ordinary function to TwoPhaseReloadable.
+type TwoPhaseFunc func(ctx context.Context) (PreparedReload, error)
+
+// Prepare calls f.
+func (f TwoPhaseFunc) Prepare(ctx context.Context) (PreparedReload, error) {
+	return f(ctx)
+}
+
+// PreparedFunc builds a PreparedReload from commit and abort functions;
+// either may be nil.
+func PreparedFunc(commit, abort func()) PreparedReload {
+	return preparedFunc{commit: commit, abort: abort}
+}
+
+type preparedFunc struct {
+	commit, abort func()
+}
+
+func (p preparedFunc) Commit() {
+	if p.commit != nil {
+		p.commit()
+	}
+}
+
+func (p preparedFunc) Abort() {
+	if p.abort != nil {
+		p.abort()
+	}
+}
+
+// ReloadTwoPhase prepares every component in order and commits them all only
+// if every Prepare succeeded and ctx is still live. Otherwise the components
+// prepared so far are aborted in reverse order, no state changes, and the
+// first error is returned.
+func ReloadTwoPhase(ctx context.Context, components ...TwoPhaseReloadable) error {
+	prepared := make([]PreparedReload, 0, len(components))
+	abort := func() {
+		for i := len(prepared) - 1; i >= 0; i-- {
+			prepared[i].Abort()
+		}
+	}
+
+	for i, component := range components {
+		if err := ctx.Err(); err != nil {
+			abort()
+			return err
+		}
+
+		p, err := prepareComponent(ctx, component)
+		if err != nil {
+			abort()
+			return fmt.Errorf("access: prepare component %d: %w", i, err)
+		}
+
+		prepared = append(prepared, p)
+	}
+
+	if err := ctx.Err(); err != nil {
+		abort()
+		return err
+	}
+
+	for _, p := range prepared {
+		p.Commit()
+	}
+
+	return nil
+}
+
+// prepareComponent calls Prepare, turning a panic into an error and a nil
+// PreparedReload into a no-op.
+func prepareComponent(ctx context.Context, component TwoPhaseReloadable) (p PreparedReload, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p, err = nil, fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	p, err = component.Prepare(ctx)
+	if err == nil && p == nil {
+		p = PreparedFunc(nil, nil)
+	}
+
+	return p, err
+}
+
+// TwoPhaseGroup reloads its components all-or-nothing. It implements
+// ReloadableContext, and AsReloadable(group) can be registered with a Manager.
+type TwoPhaseGroup []TwoPhaseReloadable
+
+// Reload implements ReloadableContext using ReloadTwoPhase.
+func (g TwoPhaseGroup) Reload(ctx context.Context) error {
+	return ReloadTwoPhase(ctx, g...)
+}
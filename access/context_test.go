@@ -0,0 +1,122 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stagedConfig is a two-phase component holding a value that only changes on commit.
+type stagedConfig struct {
+	value   string
+	next    string
+	fail    error
+	aborted int
+}
+
+func (c *stagedConfig) Prepare(context.Context) (PreparedReload, error) {
+	if c.fail != nil {
+		return nil, c.fail
+	}
+
+	next := c.next
+	return PreparedFunc(func() { c.value = next }, func() { c.aborted++ }), nil
+}
+
+func TestAsReloadableContext(t *testing.T) {
+	mock := &MockReloadable{}
+	r := AsReloadableContext(mock)
+	assert.NoError(t, r.Reload(context.Background()))
+	assert.Equal(t, 1, mock.GetReloadCount())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, r.Reload(ctx), context.Canceled)
+	assert.Equal(t, 1, mock.GetReloadCount())
+
+	assert.EqualError(t, AsReloadableContext(ReloadFunc(func() { panic("boom") })).Reload(context.Background()), "panic: boom")
+
+	l := NewIPAccessList(Allow)
+	l.path = "/nonexistent/rules"
+	assert.Error(t, AsReloadableContext(l).Reload(context.Background()))
+}
+
+func TestAsReloadable(t *testing.T) {
+	fail := errors.New("bad")
+	calls := 0
+	r := AsReloadable(ReloadableContextFunc(func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return fail
+		}
+
+		return nil
+	}))
+
+	var legacy Reloadable = r
+	legacy.Reload()
+	assert.ErrorIs(t, r.Err(), fail)
+	legacy.Reload()
+	assert.NoError(t, r.Err())
+
+	// Errors surface through a Manager
+	calls = 0
+	m := NewManager()
+	assert.NoError(t, m.Register("ctx", r))
+	report, err := m.ReloadNow("test")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, report.Err(), fail)
+}
+
+func TestReloadTwoPhase(t *testing.T) {
+	a := &stagedConfig{value: "a1", next: "a2"}
+	b := &stagedConfig{value: "b1", next: "b2"}
+	assert.NoError(t, ReloadTwoPhase(context.Background(), a, b))
+	assert.Equal(t, "a2", a.value)
+	assert.Equal(t, "b2", b.value)
+
+	// A failing component rolls back everything prepared before it
+	a.next, b.next = "a3", "b3"
+	c := &stagedConfig{value: "c1", fail: errors.New("invalid")}
+	err := ReloadTwoPhase(context.Background(), a, b, c)
+	assert.ErrorContains(t, err, "prepare component 2: invalid")
+	assert.Equal(t, "a2", a.value)
+	assert.Equal(t, "b2", b.value)
+	assert.Equal(t, 1, a.aborted)
+	assert.Equal(t, 1, b.aborted)
+
+	// Cancellation before commit aborts as well
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelling := TwoPhaseFunc(func(context.Context) (PreparedReload, error) {
+		cancel()
+		return nil, nil
+	})
+	assert.ErrorIs(t, ReloadTwoPhase(ctx, a, cancelling), context.Canceled)
+	assert.Equal(t, "a2", a.value)
+	assert.Equal(t, 2, a.aborted)
+
+	// Panics are failures
+	panicky := TwoPhaseFunc(func(context.Context) (PreparedReload, error) { panic("boom") })
+	assert.ErrorContains(t, ReloadTwoPhase(context.Background(), a, panicky), "panic: boom")
+	assert.Equal(t, 3, a.aborted)
+}
+
+func TestTwoPhaseGroup(t *testing.T) {
+	a := &stagedConfig{value: "a1", next: "a2"}
+	b := &stagedConfig{value: "b1", fail: errors.New("invalid")}
+	group := AsReloadable(TwoPhaseGroup{a, b})
+
+	m := NewManager()
+	assert.NoError(t, m.Register("configs", group))
+	report, _ := m.ReloadNow("test")
+	assert.Error(t, report.Err())
+	assert.Equal(t, "a1", a.value)
+
+	b.fail, b.next = nil, "b2"
+	report, _ = m.ReloadNow("test")
+	assert.NoError(t, report.Err())
+	assert.Equal(t, "a2", a.value)
+	assert.Equal(t, "b2", b.value)
+}
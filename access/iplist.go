@@ -0,0 +1,400 @@
+package access
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	kkutil "github.com/yetiz-org/goth-util"
+	"github.com/yetiz-org/goth-util/validate"
+)
+
+// Action is the outcome of an access rule.
+type Action int
+
+const (
+	// Deny rejects the request.
+	Deny Action = iota
+	// Allow accepts the request.
+	Allow
+)
+
+// String returns "allow" or "deny".
+func (a Action) String() string {
+	if a == Allow {
+		return "allow"
+	}
+
+	return "deny"
+}
+
+// ParseAction parses "allow" or "deny", case-insensitively.
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "allow":
+		return Allow, nil
+	case "deny":
+		return Deny, nil
+	default:
+		return Deny, fmt.Errorf("access: unknown action %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Action) UnmarshalText(text []byte) error {
+	action, err := ParseAction(string(text))
+	if err != nil {
+		return err
+	}
+
+	*a = action
+	return nil
+}
+
+// IPRule applies Action to addresses inside Network.
+type IPRule struct {
+	Action  Action
+	Network *net.IPNet
+	Label   string
+}
+
+// String formats the rule in the text file syntax.
+func (r IPRule) String() string {
+	s := r.Action.String() + " " + r.Network.String()
+	if r.Label != "" {
+		s += " " + r.Label
+	}
+
+	return s
+}
+
+// Decision is the result of checking an address against an IPAccessList.
+type Decision struct {
+	Action Action
+
+	// Rule is the first rule that matched, or nil when the default action applied.
+	Rule *IPRule
+}
+
+// Allowed reports whether the decision lets the request through.
+func (d Decision) Allowed() bool {
+	return d.Action == Allow
+}
+
+type ipRuleSet struct {
+	defaultAction Action
+	rules         []IPRule
+}
+
+// IPAccessList is an ordered list of allow and deny rules over IP networks.
+// The first rule whose network contains an address decides; when none does,
+// the default action applies.
+//
+// The zero value has no rules and denies everything until SetRules is called.
+// Rules are swapped atomically, so checks never observe a partially applied
+// rule set. A list loaded from a file implements Reloadable: Reload re-reads
+// the file and keeps the previous rules if the new file is invalid. Combine it
+// with ReloadOnSignal or WatchFile to pick up edits without a restart.
+//
+// File format (text), one directive per line:
+//
+//	# comment
+//	default deny
+//	allow 10.0.0.0/8 office network
+//	deny  10.1.2.3
+//
+// A bare address is treated as a single-host network. Everything after the
+// network is the rule label. Files ending in ".json" use the form
+//
+//	{"default": "deny", "rules": [{"action": "allow", "cidr": "10.0.0.0/8", "label": "office"}]}
+type IPAccessList struct {
+	path  string
+	rules atomic.Pointer[ipRuleSet]
+
+	errMu   sync.Mutex
+	lastErr error
+
+	// Resolver derives the client address in Middleware when the request
+	// context does not already carry one. When nil, kkutil.NewClientIPResolver()
+	// is used.
+	Resolver *kkutil.ClientIPResolver
+
+	// DenyHandler serves rejected requests. When nil, a plain 403 Forbidden is
+	// written. The decision is available through DecisionFromContext.
+	DenyHandler http.Handler
+}
+
+// NewIPAccessList creates a list with the given default action and rules.
+func NewIPAccessList(defaultAction Action, rules ...IPRule) *IPAccessList {
+	l := &IPAccessList{}
+	l.SetRules(defaultAction, rules...)
+	return l
+}
+
+// LoadIPAccessList reads a rule file and returns a list that re-reads the
+// same file on Reload.
+func LoadIPAccessList(path string) (*IPAccessList, error) {
+	l := &IPAccessList{path: path}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// ParseIPAccessList parses rules in the text or JSON format. JSON is detected
+// by a leading '{'.
+func ParseIPAccessList(data []byte) (*IPAccessList, error) {
+	set, err := parseIPRules(data, isJSON(data))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &IPAccessList{}
+	l.rules.Store(set)
+	return l, nil
+}
+
+// SetRules atomically replaces the rules and the default action.
+func (l *IPAccessList) SetRules(defaultAction Action, rules ...IPRule) {
+	l.rules.Store(&ipRuleSet{defaultAction: defaultAction, rules: append([]IPRule(nil), rules...)})
+}
+
+// Rules returns a copy of the current rules and default action.
+func (l *IPAccessList) Rules() (Action, []IPRule) {
+	set := l.ruleSet()
+	return set.defaultAction, append([]IPRule(nil), set.rules...)
+}
+
+// Path returns the file the list was loaded from, if any.
+func (l *IPAccessList) Path() string {
+	return l.path
+}
+
+// ruleSet returns the current rules, or an empty default-deny set when none
+// have been set.
+func (l *IPAccessList) ruleSet() *ipRuleSet {
+	if set := l.rules.Load(); set != nil {
+		return set
+	}
+
+	return &ipRuleSet{defaultAction: Deny}
+}
+
+// Check returns the decision for ip.
+func (l *IPAccessList) Check(ip net.IP) Decision {
+	set := l.ruleSet()
+	for i := range set.rules {
+		if validate.IsIPNetContain(set.rules[i].Network, ip) {
+			return Decision{Action: set.rules[i].Action, Rule: &set.rules[i]}
+		}
+	}
+
+	return Decision{Action: set.defaultAction}
+}
+
+// Allowed reports whether ip is allowed.
+func (l *IPAccessList) Allowed(ip net.IP) bool {
+	return l.Check(ip).Allowed()
+}
+
+// Reload re-reads the file the list was loaded from. On failure the current
+// rules stay in place and the error is available from Err. Lists that were
+// not loaded from a file are left unchanged.
+func (l *IPAccessList) Reload() {
+	if l.path == "" {
+		return
+	}
+
+	err := l.load()
+	l.errMu.Lock()
+	l.lastErr = err
+	l.errMu.Unlock()
+}
+
+// Err returns the error of the most recent Reload, or nil if it succeeded.
+func (l *IPAccessList) Err() error {
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+	return l.lastErr
+}
+
+func (l *IPAccessList) load() error {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+
+	// An empty file is far more likely a truncated write than a request to
+	// deny everyone.
+	if len(bytes.TrimSpace(data)) == 0 {
+		return fmt.Errorf("%s: empty rule file", l.path)
+	}
+
+	set, err := parseIPRules(data, strings.EqualFold(filepath.Ext(l.path), ".json") || isJSON(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", l.path, err)
+	}
+
+	l.rules.Store(set)
+	return nil
+}
+
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+type ipAccessListFile struct {
+	Default Action `json:"default"`
+	Rules   []struct {
+		Action *Action `json:"action"`
+		CIDR   string  `json:"cidr"`
+		Label  string  `json:"label"`
+	} `json:"rules"`
+}
+
+func parseIPRules(data []byte, asJSON bool) (*ipRuleSet, error) {
+	if asJSON {
+		var file ipAccessListFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+
+		set := &ipRuleSet{defaultAction: file.Default}
+		for i, rule := range file.Rules {
+			if rule.Action == nil {
+				return nil, fmt.Errorf("rule %d: missing action", i)
+			}
+
+			network := parseRuleNetwork(rule.CIDR)
+			if network == nil {
+				return nil, fmt.Errorf("rule %d: invalid network %q", i, rule.CIDR)
+			}
+
+			set.rules = append(set.rules, IPRule{Action: *rule.Action, Network: network, Label: rule.Label})
+		}
+
+		return set, nil
+	}
+
+	set := &ipRuleSet{defaultAction: Deny}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if strings.EqualFold(fields[0], "default") {
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: expected \"default allow|deny\"", line)
+			}
+
+			action, err := ParseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+
+			set.defaultAction = action
+			continue
+		}
+
+		action, err := ParseAction(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing network", line)
+		}
+
+		network := parseRuleNetwork(fields[1])
+		if network == nil {
+			return nil, fmt.Errorf("line %d: invalid network %q", line, fields[1])
+		}
+
+		set.rules = append(set.rules, IPRule{Action: action, Network: network, Label: strings.Join(fields[2:], " ")})
+	}
+
+	return set, scanner.Err()
+}
+
+// parseRuleNetwork parses a CIDR or a bare address, which becomes a host network.
+func parseRuleNetwork(s string) *net.IPNet {
+	if strings.Contains(s, "/") {
+		return validate.ParseIPNet(s)
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+type decisionContextKey struct{}
+
+// DecisionFromContext returns the decision Middleware stored for the request.
+func DecisionFromContext(ctx context.Context) (Decision, bool) {
+	decision, ok := ctx.Value(decisionContextKey{}).(Decision)
+	return decision, ok
+}
+
+// Middleware checks the client address of every request against the list.
+// It uses the ClientIP already stored by kkutil.ClientIPResolver.Middleware
+// when present and resolves it otherwise. Requests whose address cannot be
+// determined get the default action. Allowed requests reach next; rejected
+// ones go to DenyHandler. Both see the decision in their request context.
+func (l *IPAccessList) Middleware(next http.Handler) http.Handler {
+	resolver := l.Resolver
+	if resolver == nil {
+		resolver = kkutil.NewClientIPResolver()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, found := kkutil.ClientIPFromContext(r.Context())
+		if !found {
+			client = resolver.Resolve(r)
+			r = r.WithContext(kkutil.WithClientIP(r.Context(), client))
+		}
+
+		decision := Decision{Action: l.ruleSet().defaultAction}
+		if client.IP != nil {
+			decision = l.Check(client.IP)
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), decisionContextKey{}, decision))
+		if decision.Allowed() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if l.DenyHandler != nil {
+			l.DenyHandler.ServeHTTP(w, r)
+			return
+		}
+
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
@@ -0,0 +1,194 @@
+package access
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	kkutil "github.com/yetiz-org/goth-util"
+	"github.com/yetiz-org/goth-util/validate"
+)
+
+const textRules = `
+# office first, then the lab carve-out
+default allow
+deny 10.1.0.0/16 lab network
+allow 10.0.0.0/8 office
+deny 192.0.2.7
+deny 2001:db8::/32
+`
+
+func TestParseIPAccessListText(t *testing.T) {
+	l, err := ParseIPAccessList([]byte(textRules))
+	assert.NoError(t, err)
+
+	defaultAction, rules := l.Rules()
+	assert.Equal(t, Allow, defaultAction)
+	assert.Len(t, rules, 4)
+	assert.Equal(t, "deny 10.1.0.0/16 lab network", rules[0].String())
+	assert.Equal(t, "deny 192.0.2.7/32", rules[2].String())
+
+	decision := l.Check(net.ParseIP("10.1.2.3"))
+	assert.False(t, decision.Allowed())
+	assert.Equal(t, "lab network", decision.Rule.Label)
+
+	decision = l.Check(net.ParseIP("10.2.0.1"))
+	assert.True(t, decision.Allowed())
+	assert.Equal(t, "office", decision.Rule.Label)
+
+	assert.False(t, l.Allowed(net.ParseIP("192.0.2.7")))
+	assert.False(t, l.Allowed(net.ParseIP("2001:db8::1")))
+
+	decision = l.Check(net.ParseIP("8.8.8.8"))
+	assert.True(t, decision.Allowed())
+	assert.Nil(t, decision.Rule)
+}
+
+func TestParseIPAccessListJSON(t *testing.T) {
+	l, err := ParseIPAccessList([]byte(`{"default":"deny","rules":[{"action":"allow","cidr":"10.0.0.0/8","label":"office"},{"action":"allow","cidr":"::1"}]}`))
+	assert.NoError(t, err)
+	assert.True(t, l.Allowed(net.ParseIP("10.9.9.9")))
+	assert.True(t, l.Allowed(net.ParseIP("::1")))
+	assert.False(t, l.Allowed(net.ParseIP("11.0.0.1")))
+}
+
+func TestParseIPAccessListErrors(t *testing.T) {
+	for _, data := range []string{
+		"permit 10.0.0.0/8",
+		"allow",
+		"allow 10.0.0.0/33",
+		"allow not-an-ip",
+		"default",
+		"default maybe",
+		`{"rules":[{"action":"allow","cidr":"bogus"}]}`,
+		`{"default":"allow","rules":[{"cidr":"10.0.0.0/8"}]}`,
+		`{"default":"perhaps"}`,
+		`{`,
+	} {
+		_, err := ParseIPAccessList([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestIPAccessListReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"default":"deny","rules":[{"action":"allow","cidr":"10.0.0.0/8"}]}`), 0o644))
+
+	l, err := LoadIPAccessList(path)
+	assert.NoError(t, err)
+	assert.Equal(t, path, l.Path())
+	assert.True(t, l.Allowed(net.ParseIP("10.0.0.1")))
+
+	var reloadable Reloadable = l
+	assert.NoError(t, os.WriteFile(path, []byte(`{"default":"allow","rules":[{"action":"deny","cidr":"10.0.0.0/8"}]}`), 0o644))
+	reloadable.Reload()
+	assert.NoError(t, l.Err())
+	assert.False(t, l.Allowed(net.ParseIP("10.0.0.1")))
+	assert.True(t, l.Allowed(net.ParseIP("11.0.0.1")))
+
+	// A broken file keeps the previous rules
+	assert.NoError(t, os.WriteFile(path, []byte(`{"rules":[{"action":"allow","cidr":"bogus"}]}`), 0o644))
+	reloadable.Reload()
+	assert.Error(t, l.Err())
+	assert.False(t, l.Allowed(net.ParseIP("10.0.0.1")))
+	assert.True(t, l.Allowed(net.ParseIP("11.0.0.1")))
+
+	// So does an empty one, such as a file caught mid-write
+	assert.NoError(t, os.WriteFile(path, []byte(" \n"), 0o644))
+	reloadable.Reload()
+	assert.ErrorContains(t, l.Err(), "empty rule file")
+	assert.True(t, l.Allowed(net.ParseIP("11.0.0.1")))
+
+	_, err = LoadIPAccessList(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+
+	// Lists built in code ignore Reload
+	static := NewIPAccessList(Allow)
+	static.Reload()
+	assert.NoError(t, static.Err())
+	assert.True(t, static.Allowed(net.ParseIP("10.0.0.1")))
+}
+
+func TestIPAccessListMiddleware(t *testing.T) {
+	l := NewIPAccessList(Allow, IPRule{Action: Deny, Network: validate.ParseIPNet("8.8.8.0/24"), Label: "blocked resolver"})
+
+	var seen Decision
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = DecisionFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Direct public client is allowed
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "1.1.1.1:1234"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.True(t, seen.Allowed())
+
+	// Client behind a trusted proxy is denied by the resolved address
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set(kkutil.HeaderXForwardedFor, "8.8.8.8")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// A custom deny handler sees the matched rule
+	var denied Decision
+	var deniedIP kkutil.ClientIP
+	l.DenyHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		denied, _ = DecisionFromContext(r.Context())
+		deniedIP, _ = kkutil.ClientIPFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler = l.Middleware(http.NotFoundHandler())
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, "blocked resolver", denied.Rule.Label)
+	assert.Equal(t, net.ParseIP("8.8.8.8"), deniedIP.IP)
+
+	// An address already resolved upstream is reused
+	resolved := kkutil.NewClientIPResolver().Middleware(handler)
+	rec = httptest.NewRecorder()
+	resolved.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+
+	// Unknown client address falls back to the default action
+	l.SetRules(Deny)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "garbage"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Nil(t, denied.Rule)
+}
+
+func TestIPAccessListZeroValue(t *testing.T) {
+	l := &IPAccessList{}
+	assert.False(t, l.Allowed(net.ParseIP("10.0.0.1")))
+	action, rules := l.Rules()
+	assert.Equal(t, Deny, action)
+	assert.Empty(t, rules)
+
+	rec := httptest.NewRecorder()
+	l.Middleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	l.SetRules(Allow)
+	assert.True(t, l.Allowed(net.ParseIP("10.0.0.1")))
+}
+
+func TestActionText(t *testing.T) {
+	var a Action
+	assert.NoError(t, a.UnmarshalText([]byte("ALLOW")))
+	assert.Equal(t, Allow, a)
+	text, _ := Deny.MarshalText()
+	assert.Equal(t, "deny", string(text))
+	assert.Error(t, a.UnmarshalText([]byte("maybe")))
+}
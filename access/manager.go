@@ -0,0 +1,364 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDuplicateName is returned when a name is registered twice.
+	ErrDuplicateName = errors.New("access: component already registered")
+
+	// ErrUnknownDependency is returned when a component depends on an unregistered name.
+	ErrUnknownDependency = errors.New("access: unknown dependency")
+
+	// ErrDependencyCycle is returned when dependencies form a cycle.
+	ErrDependencyCycle = errors.New("access: dependency cycle")
+
+	// ErrDependencyFailed is recorded for components skipped because a dependency failed.
+	ErrDependencyFailed = errors.New("access: dependency failed")
+)
+
+// errorReporter is implemented by Reloadables that expose the outcome of
+// their last Reload, such as IPAccessList.
+type errorReporter interface {
+	Err() error
+}
+
+// Outcome is the result of reloading one component.
+type Outcome struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+
+	// Skipped is set when the component was not reloaded because one of its
+	// dependencies failed.
+	Skipped bool
+}
+
+// Report describes one reload pass over all components.
+type Report struct {
+	// Reason names what triggered the pass, such as "signal hangup" or a file path.
+	Reason   string
+	Started  time.Time
+	Duration time.Duration
+	Outcomes []Outcome
+}
+
+// Failed returns the outcomes that carry an error, including skipped ones.
+func (r Report) Failed() []Outcome {
+	var failed []Outcome
+	for _, outcome := range r.Outcomes {
+		if outcome.Err != nil {
+			failed = append(failed, outcome)
+		}
+	}
+
+	return failed
+}
+
+// Err joins the errors of all failed components, or returns nil.
+func (r Report) Err() error {
+	var errs []error
+	for _, outcome := range r.Failed() {
+		errs = append(errs, fmt.Errorf("%s: %w", outcome.Name, outcome.Err))
+	}
+
+	return errors.Join(errs...)
+}
+
+type component struct {
+	name      string
+	r         Reloadable
+	dependsOn []string
+	index     int
+}
+
+// Manager reloads a set of named Reloadables together, in dependency order.
+//
+// Reloads run one at a time. Triggers from Trigger, signals and file watchers
+// are queued for the background loop started by Start; any number of triggers
+// arriving while a reload is in progress result in a single follow-up reload.
+// ReloadNow runs a pass synchronously, waiting for a running pass to finish.
+//
+// A component fails when its Reload panics or, if it has an Err() error
+// method, when Err reports an error afterwards. Components that depend on a
+// failed component are skipped for that pass.
+//
+// Manager itself implements Reloadable and reports the outcome of its last
+// pass through Err, so managers can be nested: an inner manager with a failed
+// component counts as a failed component of the outer one.
+//
+// The zero value is an empty Manager ready to use.
+type Manager struct {
+	mu         sync.Mutex
+	components map[string]*component
+	last       Report
+	lastErr    error
+	reason     string
+
+	runMu   sync.Mutex
+	trigger chan struct{}
+
+	// OnReport, when set, is called after every pass with its report.
+	OnReport func(Report)
+}
+
+// NewManager creates an empty Manager.
+func NewManager() *Manager {
+	return &Manager{
+		components: map[string]*component{},
+		trigger:    make(chan struct{}, 1),
+	}
+}
+
+// Register adds r under name, to be reloaded after the components it depends
+// on. Dependencies may be registered later but must exist when reloading.
+func (m *Manager) Register(name string, r Reloadable, dependsOn ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.components[name]; found {
+		return fmt.Errorf("%w: %q", ErrDuplicateName, name)
+	}
+
+	if m.components == nil {
+		m.components = map[string]*component{}
+	}
+
+	m.components[name] = &component{name: name, r: r, dependsOn: append([]string(nil), dependsOn...), index: len(m.components)}
+	return nil
+}
+
+// Unregister removes the component registered under name.
+func (m *Manager) Unregister(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.components, name)
+}
+
+// Order returns the component names in the order they are reloaded:
+// dependencies first, otherwise in registration order.
+func (m *Manager) Order() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ordered, err := m.order()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(ordered))
+	for i, c := range ordered {
+		names[i] = c.name
+	}
+
+	return names, nil
+}
+
+func (m *Manager) order() ([]*component, error) {
+	all := make([]*component, 0, len(m.components))
+	for _, c := range m.components {
+		all = append(all, c)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].index < all[j].index })
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := map[string]int{}
+	ordered := make([]*component, 0, len(all))
+	var visit func(c *component, path []string) error
+	visit = func(c *component, path []string) error {
+		if state[c.name] == visited {
+			return nil
+		}
+
+		// Cap the slice so that sibling branches never share a backing array.
+		path = append(path[:len(path):len(path)], c.name)
+		if state[c.name] == visiting {
+			return fmt.Errorf("%w: %v", ErrDependencyCycle, path)
+		}
+
+		state[c.name] = visiting
+		for _, dep := range c.dependsOn {
+			d, found := m.components[dep]
+			if !found {
+				return fmt.Errorf("%w: %q needs %q", ErrUnknownDependency, c.name, dep)
+			}
+
+			if err := visit(d, path); err != nil {
+				return err
+			}
+		}
+
+		state[c.name] = visited
+		ordered = append(ordered, c)
+		return nil
+	}
+
+	for _, c := range all {
+		if err := visit(c, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// Reload implements Reloadable by running a pass synchronously. Its outcome
+// is available from Err.
+func (m *Manager) Reload() {
+	_, _ = m.ReloadNow("reload")
+}
+
+// Err returns the error of the most recent pass: the dependency graph error
+// if the pass could not run, the joined component errors if any failed, or
+// nil.
+func (m *Manager) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastErr
+}
+
+// ReloadNow runs a reload pass synchronously and returns its report. It
+// fails without reloading anything if the dependency graph is invalid.
+func (m *Manager) ReloadNow(reason string) (Report, error) {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	m.mu.Lock()
+	ordered, err := m.order()
+	onReport := m.OnReport
+	if err != nil {
+		m.lastErr = err
+	}
+
+	m.mu.Unlock()
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Reason: reason, Started: time.Now()}
+	failed := map[string]bool{}
+	for _, c := range ordered {
+		outcome := Outcome{Name: c.name}
+		for _, dep := range c.dependsOn {
+			if failed[dep] {
+				outcome.Skipped, outcome.Err = true, fmt.Errorf("%w: %s", ErrDependencyFailed, dep)
+				break
+			}
+		}
+
+		if !outcome.Skipped {
+			start := time.Now()
+			outcome.Err = reloadComponent(c.r)
+			outcome.Duration = time.Since(start)
+		}
+
+		failed[c.name] = outcome.Err != nil
+		report.Outcomes = append(report.Outcomes, outcome)
+	}
+
+	report.Duration = time.Since(report.Started)
+
+	m.mu.Lock()
+	m.last, m.lastErr = report, report.Err()
+	m.mu.Unlock()
+
+	if onReport != nil {
+		onReport(report)
+	}
+
+	return report, nil
+}
+
+// reloadComponent reloads r, turning a panic or a reported error into an error.
+func reloadComponent(r Reloadable) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	r.Reload()
+	if reporter, ok := r.(errorReporter); ok {
+		return reporter.Err()
+	}
+
+	return nil
+}
+
+// LastReport returns the report of the most recent pass.
+func (m *Manager) LastReport() Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Trigger requests an asynchronous reload, run by the loop started with
+// Start. Triggers arriving before the loop picks up the request are
+// coalesced; the reason of the latest one is reported.
+func (m *Manager) Trigger(reason string) {
+	m.mu.Lock()
+	m.reason = reason
+	trigger := m.triggers()
+	m.mu.Unlock()
+
+	select {
+	case trigger <- struct{}{}:
+	default:
+	}
+}
+
+// triggers returns the trigger channel, creating it for a zero Manager. It
+// must be called with mu held.
+func (m *Manager) triggers() chan struct{} {
+	if m.trigger == nil {
+		m.trigger = make(chan struct{}, 1)
+	}
+
+	return m.trigger
+}
+
+// Start runs the trigger loop until ctx is done.
+func (m *Manager) Start(ctx context.Context) {
+	m.mu.Lock()
+	trigger := m.triggers()
+	m.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-trigger:
+				m.mu.Lock()
+				reason := m.reason
+				m.mu.Unlock()
+				_, _ = m.ReloadNow(reason)
+			}
+		}
+	}()
+}
+
+// WatchSignals triggers a reload whenever one of sigs arrives, or SIGHUP
+// when none are given. The returned function stops listening.
+func (m *Manager) WatchSignals(sigs ...os.Signal) (stop func()) {
+	return notifySignals(func(sig os.Signal) { m.Trigger("signal " + sig.String()) }, sigs...)
+}
+
+// WatchFile triggers a reload when path changes, polling every interval.
+// The returned function stops polling.
+func (m *Manager) WatchFile(path string, interval time.Duration) (stop func()) {
+	return WatchFile(path, interval, ReloadFunc(func() { m.Trigger(path) }))
+}
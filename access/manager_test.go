@@ -0,0 +1,235 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingReloadable appends its name to a shared log on every Reload.
+type recordingReloadable struct {
+	name string
+	log  *[]string
+	mu   *sync.Mutex
+	err  error
+}
+
+func (r *recordingReloadable) Reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.log = append(*r.log, r.name)
+}
+
+func (r *recordingReloadable) Err() error {
+	return r.err
+}
+
+func TestManagerOrder(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	newComponent := func(name string) *recordingReloadable {
+		return &recordingReloadable{name: name, log: &log, mu: &mu}
+	}
+
+	m := NewManager()
+	assert.NoError(t, m.Register("handlers", newComponent("handlers"), "config", "acl"))
+	assert.NoError(t, m.Register("acl", newComponent("acl"), "config"))
+	assert.NoError(t, m.Register("config", newComponent("config")))
+	assert.NoError(t, m.Register("metrics", newComponent("metrics")))
+	assert.ErrorIs(t, m.Register("config", newComponent("config")), ErrDuplicateName)
+
+	order, err := m.Order()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"config", "acl", "handlers", "metrics"}, order)
+
+	report, err := m.ReloadNow("test")
+	assert.NoError(t, err)
+	assert.Equal(t, order, log)
+	assert.Equal(t, "test", report.Reason)
+	assert.Len(t, report.Outcomes, 4)
+	assert.NoError(t, report.Err())
+	assert.Equal(t, report, m.LastReport())
+
+	m.Unregister("metrics")
+	order, _ = m.Order()
+	assert.Equal(t, []string{"config", "acl", "handlers"}, order)
+}
+
+func TestManagerInvalidGraph(t *testing.T) {
+	m := NewManager()
+	assert.NoError(t, m.Register("a", ReloadFunc(func() {}), "b"))
+	_, err := m.Order()
+	assert.ErrorIs(t, err, ErrUnknownDependency)
+
+	assert.NoError(t, m.Register("b", ReloadFunc(func() {}), "c"))
+	assert.NoError(t, m.Register("c", ReloadFunc(func() {}), "a"))
+	_, err = m.ReloadNow("test")
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+	assert.EqualError(t, err, "access: dependency cycle: [a b c a]")
+	assert.ErrorIs(t, m.Err(), ErrDependencyCycle)
+
+	// Sibling branches do not overwrite each other's path.
+	m = NewManager()
+	assert.NoError(t, m.Register("root", ReloadFunc(func() {}), "x", "y"))
+	assert.NoError(t, m.Register("x", ReloadFunc(func() {}), "leaf1"))
+	assert.NoError(t, m.Register("leaf1", ReloadFunc(func() {}), "leaf2"))
+	assert.NoError(t, m.Register("leaf2", ReloadFunc(func() {})))
+	assert.NoError(t, m.Register("y", ReloadFunc(func() {}), "z"))
+	assert.NoError(t, m.Register("z", ReloadFunc(func() {}), "y"))
+	_, err = m.Order()
+	assert.EqualError(t, err, "access: dependency cycle: [root y z y]")
+}
+
+func TestManagerFailures(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	broken := &recordingReloadable{name: "config", log: &log, mu: &mu, err: errors.New("bad config")}
+
+	m := NewManager()
+	var reports []Report
+	m.OnReport = func(r Report) { reports = append(reports, r) }
+	assert.NoError(t, m.Register("config", broken))
+	assert.NoError(t, m.Register("acl", &recordingReloadable{name: "acl", log: &log, mu: &mu}, "config"))
+	assert.NoError(t, m.Register("panicky", ReloadFunc(func() { panic("boom") })))
+	assert.NoError(t, m.Register("fine", &recordingReloadable{name: "fine", log: &log, mu: &mu}))
+
+	report, err := m.ReloadNow("test")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"config", "fine"}, log)
+
+	failed := report.Failed()
+	assert.Len(t, failed, 3)
+	assert.Equal(t, "config", failed[0].Name)
+	assert.EqualError(t, failed[0].Err, "bad config")
+	assert.Equal(t, "acl", failed[1].Name)
+	assert.True(t, failed[1].Skipped)
+	assert.ErrorIs(t, failed[1].Err, ErrDependencyFailed)
+	assert.Equal(t, "panicky", failed[2].Name)
+	assert.EqualError(t, failed[2].Err, "panic: boom")
+	assert.ErrorContains(t, report.Err(), "config: bad config")
+	assert.Len(t, reports, 1)
+
+	// The manager is itself Reloadable
+	var r Reloadable = m
+	r.Reload()
+	assert.Len(t, reports, 2)
+	assert.Equal(t, "reload", reports[1].Reason)
+	assert.ErrorContains(t, m.Err(), "config: bad config")
+}
+
+func TestManagerNested(t *testing.T) {
+	healthy := true
+	inner := NewManager()
+	assert.NoError(t, inner.Register("db", ReloadFunc(func() {
+		if !healthy {
+			panic("db down")
+		}
+	})))
+
+	var reloaded bool
+	outer := NewManager()
+	assert.NoError(t, outer.Register("inner", inner))
+	assert.NoError(t, outer.Register("cache", ReloadFunc(func() { reloaded = true }), "inner"))
+
+	report, err := outer.ReloadNow("test")
+	assert.NoError(t, err)
+	assert.NoError(t, report.Err())
+	assert.NoError(t, outer.Err())
+	assert.True(t, reloaded)
+
+	healthy, reloaded = false, false
+	report, _ = outer.ReloadNow("test")
+	assert.ErrorContains(t, report.Err(), "inner: db: panic: db down")
+	assert.True(t, report.Outcomes[1].Skipped)
+	assert.False(t, reloaded)
+	assert.Error(t, outer.Err())
+}
+
+func TestManagerZero(t *testing.T) {
+	var m Manager
+	order, err := m.Order()
+	assert.NoError(t, err)
+	assert.Empty(t, order)
+
+	var runs atomic.Int32
+	assert.NoError(t, m.Register("counter", ReloadFunc(func() { runs.Add(1) })))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+	m.Trigger("test")
+	assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, m.Err())
+}
+
+func TestManagerCoalescesTriggers(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	var runs atomic.Int32
+	m := NewManager()
+	assert.NoError(t, m.Register("slow", ReloadFunc(func() {
+		runs.Add(1)
+		started <- struct{}{}
+		<-release
+	})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	m.Trigger("first")
+	<-started
+
+	// Triggers during a running reload collapse into one more pass
+	for i := 0; i < 5; i++ {
+		m.Trigger("again")
+	}
+
+	// A synchronous reload waits for the running pass instead of overlapping
+	syncDone := make(chan Report)
+	go func() {
+		report, _ := m.ReloadNow("sync")
+		syncDone <- report
+	}()
+
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+
+	<-syncDone
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(3), runs.Load())
+}
+
+func TestManagerWatchers(t *testing.T) {
+	var runs atomic.Int32
+	m := NewManager()
+	assert.NoError(t, m.Register("counter", ReloadFunc(func() { runs.Add(1) })))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	stopSignals := m.WatchSignals(syscall.SIGUSR2)
+	defer stopSignals()
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return m.LastReport().Reason == "signal user defined signal 2" }, time.Second, 5*time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "config")
+	stopFile := m.WatchFile(path, 5*time.Millisecond)
+	defer stopFile()
+	assert.NoError(t, os.WriteFile(path, []byte("x"), 0o644))
+	assert.Eventually(t, func() bool { return runs.Load() == 2 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return m.LastReport().Reason == path }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,222 @@
+package access
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReloadEvent describes one reload of a named component.
+type ReloadEvent struct {
+	Name     string
+	Time     time.Time
+	Duration time.Duration
+	Err      error
+
+	// Skipped is set when a Manager skipped the component because one of its
+	// dependencies failed.
+	Skipped bool
+}
+
+// LogValue implements slog.LogValuer.
+func (e ReloadEvent) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("name", e.Name),
+		slog.Duration("duration", e.Duration),
+	}
+
+	if e.Skipped {
+		attrs = append(attrs, slog.Bool("skipped", true))
+	}
+
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// ReloadStats accumulates the reloads of one component.
+type ReloadStats struct {
+	Name          string        `json:"name"`
+	Count         uint64        `json:"count"`
+	Failures      uint64        `json:"failures"`
+	LastDuration  time.Duration `json:"last_duration_ns"`
+	TotalDuration time.Duration `json:"total_duration_ns"`
+	LastSuccess   time.Time     `json:"last_success"`
+	LastFailure   time.Time     `json:"last_failure"`
+	LastError     string        `json:"last_error,omitempty"`
+}
+
+// Observer records reload statistics per component and forwards every event
+// to OnEvent. Use Wrap for individual Reloadables or RecordReport as a
+// Manager's OnReport to observe all of its components. The zero value is an
+// empty Observer ready to use.
+type Observer struct {
+	mu    sync.Mutex
+	stats map[string]*ReloadStats
+
+	// Clock is the time source. When nil, SystemClock is used.
+	Clock Clock
+
+	// OnEvent, when set, is called after every recorded event, for example
+	// with SlogHook.
+	OnEvent func(ReloadEvent)
+}
+
+// NewObserver creates an empty Observer.
+func NewObserver() *Observer {
+	return &Observer{stats: map[string]*ReloadStats{}}
+}
+
+// Record adds event to the statistics of its component.
+func (o *Observer) Record(event ReloadEvent) {
+	o.mu.Lock()
+	stats, found := o.stats[event.Name]
+	if !found {
+		if o.stats == nil {
+			o.stats = map[string]*ReloadStats{}
+		}
+
+		stats = &ReloadStats{Name: event.Name}
+		o.stats[event.Name] = stats
+	}
+
+	stats.Count++
+	stats.LastDuration = event.Duration
+	stats.TotalDuration += event.Duration
+	if event.Err != nil {
+		stats.Failures++
+		stats.LastFailure = event.Time
+		stats.LastError = event.Err.Error()
+	} else {
+		stats.LastSuccess = event.Time
+		stats.LastError = ""
+	}
+
+	onEvent := o.OnEvent
+	o.mu.Unlock()
+
+	if onEvent != nil {
+		onEvent(event)
+	}
+}
+
+// RecordReport records every outcome of a Manager pass. Assign it to
+// Manager.OnReport.
+func (o *Observer) RecordReport(report Report) {
+	at := report.Started
+	for _, outcome := range report.Outcomes {
+		at = at.Add(outcome.Duration)
+		o.Record(ReloadEvent{Name: outcome.Name, Time: at, Duration: outcome.Duration, Err: outcome.Err, Skipped: outcome.Skipped})
+	}
+}
+
+// Stats returns the statistics of the named component.
+func (o *Observer) Stats(name string) (ReloadStats, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats, found := o.stats[name]
+	if !found {
+		return ReloadStats{}, false
+	}
+
+	return *stats, true
+}
+
+// All returns the statistics of every component, sorted by name.
+func (o *Observer) All() []ReloadStats {
+	o.mu.Lock()
+	all := make([]ReloadStats, 0, len(o.stats))
+	for _, stats := range o.stats {
+		all = append(all, *stats)
+	}
+	o.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// publishMu serialises the check and the publish in Observer.Publish, since
+// expvar.Publish panics on a duplicate name.
+var publishMu sync.Mutex
+
+// Publish exposes the statistics through expvar under name, as an object
+// keyed by component name. It fails if name is already published.
+func (o *Observer) Publish(name string) error {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("access: expvar %q already published", name)
+	}
+
+	expvar.Publish(name, expvar.Func(func() any {
+		all := map[string]ReloadStats{}
+		for _, stats := range o.All() {
+			all[stats.Name] = stats
+		}
+
+		return all
+	}))
+
+	return nil
+}
+
+// Wrap returns a Reloadable that reloads r and records the outcome under name.
+// Failures are detected as by Manager, and the wrapper reports them from its
+// own Err method, so it can still be registered with a Manager.
+func (o *Observer) Wrap(name string, r Reloadable) Reloadable {
+	return &observedReloadable{observer: o, name: name, r: r}
+}
+
+type observedReloadable struct {
+	observer *Observer
+	name     string
+	r        Reloadable
+
+	mu  sync.Mutex
+	err error
+}
+
+func (w *observedReloadable) Reload() {
+	clock := clockOrSystem(w.observer.Clock)
+	start := clock.Now()
+	err := reloadComponent(w.r)
+	end := clock.Now()
+
+	w.mu.Lock()
+	w.err = err
+	w.mu.Unlock()
+
+	w.observer.Record(ReloadEvent{Name: w.name, Time: end, Duration: end.Sub(start), Err: err})
+}
+
+func (w *observedReloadable) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// SlogHook returns an OnEvent hook logging successful reloads at Info and
+// failed or skipped ones at Error. A nil logger means slog.Default().
+func SlogHook(logger *slog.Logger) func(ReloadEvent) {
+	return func(event ReloadEvent) {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+
+		level, msg := slog.LevelInfo, "reload succeeded"
+		if event.Err != nil {
+			level, msg = slog.LevelError, "reload failed"
+		}
+
+		l.LogAttrs(context.Background(), level, msg, slog.Any("reload", event))
+	}
+}
@@ -0,0 +1,189 @@
+package access
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yetiz-org/goth-util/internal/clock/clocktest"
+)
+
+// slowReloadable advances a manual clock on every Reload of its script.
+type slowReloadable struct {
+	*scriptedReloadable
+	clock *clocktest.Manual
+	took  time.Duration
+}
+
+func (s *slowReloadable) Reload() {
+	s.clock.Advance(s.took)
+	s.scriptedReloadable.Reload()
+}
+
+func TestObserverWrap(t *testing.T) {
+	clock := clocktest.NewManual()
+	o := NewObserver()
+	o.Clock = clock
+
+	var events []ReloadEvent
+	o.OnEvent = func(e ReloadEvent) { events = append(events, e) }
+
+	r := o.Wrap("flags", &slowReloadable{
+		scriptedReloadable: &scriptedReloadable{results: []error{nil, errors.New("bad")}},
+		clock:              clock,
+		took:               time.Second,
+	})
+
+	_, found := o.Stats("flags")
+	assert.False(t, found)
+
+	r.Reload()
+	successAt := clock.Now()
+	assert.NoError(t, r.(errorReporter).Err())
+
+	r.Reload()
+	assert.EqualError(t, r.(errorReporter).Err(), "bad")
+
+	stats, found := o.Stats("flags")
+	assert.True(t, found)
+	assert.Equal(t, uint64(2), stats.Count)
+	assert.Equal(t, uint64(1), stats.Failures)
+	assert.Equal(t, time.Second, stats.LastDuration)
+	assert.Equal(t, 2*time.Second, stats.TotalDuration)
+	assert.Equal(t, successAt, stats.LastSuccess)
+	assert.Equal(t, clock.Now(), stats.LastFailure)
+	assert.Equal(t, "bad", stats.LastError)
+
+	assert.Len(t, events, 2)
+	assert.NoError(t, events[0].Err)
+	assert.EqualError(t, events[1].Err, "bad")
+
+	m := NewManager()
+	assert.NoError(t, m.Register("flags", r))
+	report, _ := m.ReloadNow("test")
+	assert.NoError(t, report.Err())
+}
+
+func TestObserverRecord(t *testing.T) {
+	o := NewObserver()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	o.Record(ReloadEvent{Name: "b", Time: at, Duration: time.Second})
+	o.Record(ReloadEvent{Name: "b", Time: at.Add(time.Minute), Duration: 2 * time.Second, Err: errors.New("bad")})
+	o.Record(ReloadEvent{Name: "a", Time: at, Duration: time.Millisecond})
+
+	stats, found := o.Stats("b")
+	assert.True(t, found)
+	assert.Equal(t, ReloadStats{
+		Name:          "b",
+		Count:         2,
+		Failures:      1,
+		LastDuration:  2 * time.Second,
+		TotalDuration: 3 * time.Second,
+		LastSuccess:   at,
+		LastFailure:   at.Add(time.Minute),
+		LastError:     "bad",
+	}, stats)
+
+	all := o.All()
+	assert.Len(t, all, 2)
+	assert.Equal(t, "a", all[0].Name)
+	assert.Equal(t, "b", all[1].Name)
+
+	o.Record(ReloadEvent{Name: "b", Time: at.Add(2 * time.Minute)})
+	stats, _ = o.Stats("b")
+	assert.Equal(t, "", stats.LastError)
+	assert.Equal(t, at.Add(2*time.Minute), stats.LastSuccess)
+}
+
+func TestObserverZero(t *testing.T) {
+	var o Observer
+	_, found := o.Stats("config")
+	assert.False(t, found)
+	assert.Empty(t, o.All())
+
+	o.Record(ReloadEvent{Name: "config", Err: errors.New("bad")})
+	stats, found := o.Stats("config")
+	assert.True(t, found)
+	assert.Equal(t, uint64(1), stats.Failures)
+
+	o.Wrap("acl", ReloadFunc(func() {})).Reload()
+	assert.Len(t, o.All(), 2)
+}
+
+func TestObserverRecordReport(t *testing.T) {
+	m := NewManager()
+	assert.NoError(t, m.Register("base", &scriptedReloadable{results: []error{errors.New("down")}}))
+	assert.NoError(t, m.Register("derived", ReloadFunc(func() {}), "base"))
+
+	o := NewObserver()
+	m.OnReport = o.RecordReport
+	_, err := m.ReloadNow("test")
+	assert.NoError(t, err)
+
+	base, _ := o.Stats("base")
+	assert.Equal(t, "down", base.LastError)
+	derived, _ := o.Stats("derived")
+	assert.Equal(t, uint64(1), derived.Failures)
+	assert.Contains(t, derived.LastError, ErrDependencyFailed.Error())
+}
+
+func TestObserverPublish(t *testing.T) {
+	o := NewObserver()
+	o.Record(ReloadEvent{Name: "flags", Duration: time.Second, Err: errors.New("bad")})
+
+	// expvar names are process-wide, so repeated runs need fresh ones.
+	name := fmt.Sprintf("access_test_reloads_%d", time.Now().UnixNano())
+	assert.NoError(t, o.Publish(name))
+	assert.Error(t, o.Publish(name))
+
+	var published map[string]ReloadStats
+	assert.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &published))
+	assert.Equal(t, uint64(1), published["flags"].Failures)
+	assert.Equal(t, "bad", published["flags"].LastError)
+
+	// Racing publishers of one name get an error, not a panic.
+	name += "_race"
+	var wg sync.WaitGroup
+	var wins atomic.Int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if o.Publish(name) == nil {
+				wins.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, int32(1), wins.Load())
+}
+
+func TestSlogHook(t *testing.T) {
+	var buf bytes.Buffer
+	hook := SlogHook(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	hook(ReloadEvent{Name: "flags", Duration: time.Second})
+	hook(ReloadEvent{Name: "flags", Err: errors.New("bad"), Skipped: true})
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	var first, second map[string]any
+	assert.NoError(t, json.Unmarshal(lines[0], &first))
+	assert.NoError(t, json.Unmarshal(lines[1], &second))
+	assert.Equal(t, "INFO", first["level"])
+	assert.Equal(t, "reload succeeded", first["msg"])
+	assert.Equal(t, map[string]any{"name": "flags", "duration": float64(time.Second)}, first["reload"])
+	assert.Equal(t, "ERROR", second["level"])
+	assert.Equal(t, map[string]any{"name": "flags", "duration": float64(0), "skipped": true, "error": "bad"}, second["reload"])
+}
@@ -0,0 +1,426 @@
+package access
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	kkutil "github.com/yetiz-org/goth-util"
+)
+
+// DefaultMaxKeys is the number of keys a limiter tracks when MaxKeys is zero.
+const DefaultMaxKeys = 10000
+
+// RateLimitResult is the outcome of a rate limit check.
+type RateLimitResult struct {
+	Allowed bool
+
+	// Limit is the number of requests allowed per Window.
+	Limit  int
+	Window time.Duration
+
+	// Remaining is the number of further requests allowed right now.
+	Remaining int
+
+	// Reset is the time until the limit is fully available again.
+	Reset time.Duration
+
+	// RetryAfter is the time until the denied request would be allowed. It is
+	// zero for allowed requests and for requests larger than Limit, which are
+	// never allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimiter limits events per key, such as a client address or user ID.
+type RateLimiter interface {
+	// AllowN reports whether n events for key may happen now and, if so,
+	// records them.
+	AllowN(key string, n int) RateLimitResult
+}
+
+// evictScan is the number of least recently used keys a full keyedStore
+// looks at for one that is not over its limit.
+const evictScan = 8
+
+// keyedStore holds per-key limiter state, at most maxKeys keys of it, and
+// evicts the least recently used key to make room. Keys that are over their
+// limit are passed over among the evictScan oldest ones, since evicting them
+// would let them start afresh. get must be called with mu held; len and
+// forget lock it themselves.
+type keyedStore[S any] struct {
+	mu    sync.Mutex
+	order list.List
+	items map[string]*list.Element
+}
+
+type keyedEntry[S any] struct {
+	key   string
+	state S
+}
+
+// get returns the state of key, creating it if needed. limited reports
+// whether a key is over its limit.
+func (s *keyedStore[S]) get(key string, maxKeys int, limited func(*S) bool) *S {
+	if s.items == nil {
+		s.items = map[string]*list.Element{}
+	}
+
+	if e, found := s.items[key]; found {
+		s.order.MoveToFront(e)
+		return &e.Value.(*keyedEntry[S]).state
+	}
+
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+
+	for s.order.Len() >= maxKeys {
+		victim := s.order.Back()
+		for e, i := victim, 0; e != nil && i < evictScan; e, i = e.Prev(), i+1 {
+			if !limited(&e.Value.(*keyedEntry[S]).state) {
+				victim = e
+				break
+			}
+		}
+
+		delete(s.items, victim.Value.(*keyedEntry[S]).key)
+		s.order.Remove(victim)
+	}
+
+	entry := &keyedEntry[S]{key: key}
+	s.items[key] = s.order.PushFront(entry)
+	return &entry.state
+}
+
+func (s *keyedStore[S]) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *keyedStore[S]) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, found := s.items[key]; found {
+		s.order.Remove(e)
+		delete(s.items, key)
+	}
+}
+
+// limiterConfig is shared by the limiter implementations.
+type limiterConfig struct {
+	limit  int
+	window time.Duration
+
+	// Clock is the time source. When nil, SystemClock is used.
+	Clock Clock
+
+	// MaxKeys bounds the number of tracked keys. When zero, DefaultMaxKeys is
+	// used. A new key evicts the least recently used key, preferring one that
+	// is not over its limit.
+	MaxKeys int
+}
+
+func newLimiterConfig(limit int, window time.Duration) limiterConfig {
+	return limiterConfig{limit: max(limit, 1), window: max(window, time.Nanosecond)}
+}
+
+func (c *limiterConfig) result(allowed bool) RateLimitResult {
+	return RateLimitResult{Allowed: allowed, Limit: c.limit, Window: c.window}
+}
+
+// TokenBucket allows bursts of up to limit events and refills at limit events
+// per window.
+type TokenBucket struct {
+	limiterConfig
+	store keyedStore[tokenBucketState]
+}
+
+type tokenBucketState struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a TokenBucket allowing limit events per window.
+func NewTokenBucket(limit int, window time.Duration) *TokenBucket {
+	return &TokenBucket{limiterConfig: newLimiterConfig(limit, window)}
+}
+
+// Allow is AllowN(key, 1).
+func (l *TokenBucket) Allow(key string) RateLimitResult {
+	return l.AllowN(key, 1)
+}
+
+// AllowN implements RateLimiter.
+func (l *TokenBucket) AllowN(key string, n int) RateLimitResult {
+	now := clockOrSystem(l.Clock).Now()
+	perToken := float64(l.window) / float64(l.limit)
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	state := l.store.get(key, l.MaxKeys, func(s *tokenBucketState) bool {
+		return l.tokens(s, now, perToken) < 1
+	})
+	state.tokens = l.tokens(state, now, perToken)
+	state.last = now
+
+	result := l.result(n <= l.limit && state.tokens >= float64(n))
+	if result.Allowed {
+		state.tokens -= float64(n)
+	} else if n <= l.limit {
+		result.RetryAfter = time.Duration(math.Ceil((float64(n) - state.tokens) * perToken))
+	}
+
+	result.Remaining = int(state.tokens)
+	result.Reset = time.Duration(math.Ceil((float64(l.limit) - state.tokens) * perToken))
+	return result
+}
+
+// tokens returns the tokens of s refilled up to now.
+func (l *TokenBucket) tokens(s *tokenBucketState, now time.Time, perToken float64) float64 {
+	if s.last.IsZero() {
+		return float64(l.limit)
+	}
+
+	if elapsed := now.Sub(s.last); elapsed > 0 {
+		return math.Min(float64(l.limit), s.tokens+float64(elapsed)/perToken)
+	}
+
+	return s.tokens
+}
+
+// Len returns the number of tracked keys.
+func (l *TokenBucket) Len() int {
+	return l.store.len()
+}
+
+// Forget drops the state of key.
+func (l *TokenBucket) Forget(key string) {
+	l.store.forget(key)
+}
+
+// GCRA implements the generic cell rate algorithm: events are spaced
+// window/limit apart, with a burst of up to limit events. It behaves like
+// TokenBucket but stores a single timestamp per key.
+type GCRA struct {
+	limiterConfig
+	store keyedStore[time.Time]
+}
+
+// NewGCRA creates a GCRA limiter allowing limit events per window.
+func NewGCRA(limit int, window time.Duration) *GCRA {
+	return &GCRA{limiterConfig: newLimiterConfig(limit, window)}
+}
+
+// Allow is AllowN(key, 1).
+func (l *GCRA) Allow(key string) RateLimitResult {
+	return l.AllowN(key, 1)
+}
+
+// AllowN implements RateLimiter.
+func (l *GCRA) AllowN(key string, n int) RateLimitResult {
+	now := clockOrSystem(l.Clock).Now()
+
+	// A window shorter than limit nanoseconds would make the interval zero;
+	// the burst is then limit events one nanosecond apart.
+	interval := max(l.window/time.Duration(l.limit), time.Nanosecond)
+	burst := time.Duration(l.limit) * interval
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	// tat is the theoretical arrival time: when the key is fully recovered.
+	tat := l.store.get(key, l.MaxKeys, func(tat *time.Time) bool {
+		return tat.Add(interval - burst).After(now)
+	})
+
+	if tat.Before(now) {
+		*tat = now
+	}
+
+	next := tat.Add(time.Duration(n) * interval)
+	allowAt := next.Add(-burst)
+
+	result := l.result(n <= l.limit && !now.Before(allowAt))
+	if result.Allowed {
+		*tat = next
+	} else if n <= l.limit {
+		result.RetryAfter = allowAt.Sub(now)
+	}
+
+	result.Reset = tat.Sub(now)
+	result.Remaining = max(int((burst-result.Reset)/interval), 0)
+	return result
+}
+
+// Len returns the number of tracked keys.
+func (l *GCRA) Len() int {
+	return l.store.len()
+}
+
+// Forget drops the state of key.
+func (l *GCRA) Forget(key string) {
+	l.store.forget(key)
+}
+
+// SlidingWindow counts events in fixed windows and estimates the rate over the
+// last window by weighting the previous window's count by its overlap. It
+// needs two counters per key, unlike an exact log of event times.
+type SlidingWindow struct {
+	limiterConfig
+	store keyedStore[slidingWindowState]
+}
+
+type slidingWindowState struct {
+	start    time.Time
+	previous int
+	current  int
+}
+
+// NewSlidingWindow creates a SlidingWindow allowing limit events per window.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{limiterConfig: newLimiterConfig(limit, window)}
+}
+
+// Allow is AllowN(key, 1).
+func (l *SlidingWindow) Allow(key string) RateLimitResult {
+	return l.AllowN(key, 1)
+}
+
+// AllowN implements RateLimiter.
+func (l *SlidingWindow) AllowN(key string, n int) RateLimitResult {
+	now := clockOrSystem(l.Clock).Now()
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	state := l.store.get(key, l.MaxKeys, func(s *slidingWindowState) bool {
+		advanced := *s
+		return l.advance(&advanced, now)+1 > float64(l.limit)
+	})
+	estimate := l.advance(state, now)
+	elapsed := now.Sub(state.start)
+
+	result := l.result(n <= l.limit && estimate+float64(n) <= float64(l.limit))
+	if result.Allowed {
+		state.current += n
+		estimate += float64(n)
+	} else if n <= l.limit {
+		result.RetryAfter = l.retryAfter(state, elapsed, n)
+	}
+
+	result.Remaining = max(int(float64(l.limit)-estimate), 0)
+	result.Reset = l.window - elapsed
+	return result
+}
+
+// advance moves state to the window holding now and returns the estimated
+// number of events in the last window.
+func (l *SlidingWindow) advance(state *slidingWindowState, now time.Time) float64 {
+	start := now.Truncate(l.window)
+	switch {
+	case state.start.Equal(start):
+	case state.start.Add(l.window).Equal(start):
+		state.previous, state.current = state.current, 0
+	default:
+		state.previous, state.current = 0, 0
+	}
+
+	state.start = start
+	weight := 1 - float64(now.Sub(start))/float64(l.window)
+	return float64(state.previous)*weight + float64(state.current)
+}
+
+// retryAfter returns how long until the estimate leaves room for n events.
+func (l *SlidingWindow) retryAfter(state *slidingWindowState, elapsed time.Duration, n int) time.Duration {
+	window := float64(l.window)
+	room := float64(l.limit - n)
+	if float64(state.current) > room {
+		// Wait for the next window, where the current count decays.
+		decay := window * (1 - room/float64(state.current))
+		return l.window - elapsed + time.Duration(math.Ceil(decay))
+	}
+
+	// The previous window's share must decay below the remaining room.
+	until := window * (1 - (room-float64(state.current))/float64(state.previous))
+	return max(time.Duration(math.Ceil(until))-elapsed, time.Nanosecond)
+}
+
+// Len returns the number of tracked keys.
+func (l *SlidingWindow) Len() int {
+	return l.store.len()
+}
+
+// Forget drops the state of key.
+func (l *SlidingWindow) Forget(key string) {
+	l.store.forget(key)
+}
+
+// KeyFunc derives the rate limit key of a request. An empty key exempts the
+// request from limiting.
+type KeyFunc func(r *http.Request) string
+
+// ClientIPKey keys requests by client address, using the ClientIP stored by
+// kkutil.ClientIPResolver.Middleware when present and resolver otherwise. A
+// nil resolver means kkutil.NewClientIPResolver().
+func ClientIPKey(resolver *kkutil.ClientIPResolver) KeyFunc {
+	if resolver == nil {
+		resolver = kkutil.NewClientIPResolver()
+	}
+
+	return func(r *http.Request) string {
+		client, found := kkutil.ClientIPFromContext(r.Context())
+		if !found {
+			client = resolver.Resolve(r)
+		}
+
+		if client.IP == nil {
+			return ""
+		}
+
+		return client.IP.String()
+	}
+}
+
+// RateLimitMiddleware limits requests per key. Every limited response carries
+// the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers; rejected requests get 429 Too Many Requests with
+// Retry-After. A nil key means ClientIPKey(nil).
+func RateLimitMiddleware(l RateLimiter, key KeyFunc) func(http.Handler) http.Handler {
+	if key == nil {
+		key = ClientIPKey(nil)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result := l.AllowN(k, 1)
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+			header.Set("RateLimit-Policy", strconv.Itoa(result.Limit)+";w="+strconv.FormatInt(ceilSeconds(result.Window), 10))
+			if result.Allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header.Set("Retry-After", strconv.FormatInt(max(ceilSeconds(result.RetryAfter), 1), 10))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,226 @@
+package access
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yetiz-org/goth-util/internal/clock/clocktest"
+)
+
+func TestTokenBucket(t *testing.T) {
+	clock := clocktest.NewManual()
+	l := NewTokenBucket(3, 3*time.Second)
+	l.Clock = clock
+
+	for i := 2; i >= 0; i-- {
+		result := l.Allow("a")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+		assert.Equal(t, 3, result.Limit)
+	}
+
+	result := l.Allow("a")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+	assert.True(t, l.Allow("b").Allowed)
+
+	clock.Advance(time.Second)
+	assert.True(t, l.Allow("a").Allowed)
+	assert.False(t, l.Allow("a").Allowed)
+
+	clock.Advance(time.Hour)
+	result = l.AllowN("a", 3)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result = l.AllowN("a", 4)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Duration(0), result.RetryAfter)
+
+	assert.Equal(t, 2, l.Len())
+	l.Forget("a")
+	assert.Equal(t, 1, l.Len())
+}
+
+func TestGCRA(t *testing.T) {
+	clock := clocktest.NewManual()
+	l := NewGCRA(3, 3*time.Second)
+	l.Clock = clock
+
+	for i := 2; i >= 0; i-- {
+		result := l.Allow("a")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := l.Allow("a")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+	assert.Equal(t, 0, result.Remaining)
+
+	clock.Advance(500 * time.Millisecond)
+	result = l.Allow("a")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	clock.Advance(500 * time.Millisecond)
+	assert.True(t, l.Allow("a").Allowed)
+	assert.False(t, l.Allow("a").Allowed)
+
+	clock.Advance(time.Hour)
+	assert.True(t, l.AllowN("a", 3).Allowed)
+	assert.False(t, l.AllowN("b", 4).Allowed)
+	assert.True(t, l.AllowN("b", 3).Allowed)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := clocktest.NewManual()
+	l := NewSlidingWindow(4, 10*time.Second)
+	l.Clock = clock
+
+	for i := 3; i >= 0; i-- {
+		result := l.Allow("a")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := l.Allow("a")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 10*time.Second, result.Reset)
+	// In the next window the previous count of 4 must decay to 3.
+	assert.Equal(t, 10*time.Second+2500*time.Millisecond, result.RetryAfter)
+
+	clock.Advance(10 * time.Second)
+	assert.False(t, l.Allow("a").Allowed)
+
+	clock.Advance(2500 * time.Millisecond)
+	assert.True(t, l.Allow("a").Allowed)
+
+	// previous 4, current 1: room for one more when 4*w <= 2, at half the window.
+	result = l.Allow("a")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 2500*time.Millisecond, result.RetryAfter)
+	clock.Advance(result.RetryAfter)
+	assert.True(t, l.Allow("a").Allowed)
+
+	clock.Advance(time.Hour)
+	assert.True(t, l.AllowN("a", 4).Allowed)
+	assert.False(t, l.AllowN("a", 5).Allowed)
+}
+
+func TestLimiterEviction(t *testing.T) {
+	clock := clocktest.NewManual()
+	l := NewTokenBucket(1, time.Minute)
+	l.Clock = clock
+	l.MaxKeys = 2
+
+	assert.True(t, l.Allow("a").Allowed)
+	assert.True(t, l.Allow("b").Allowed)
+	clock.Advance(time.Minute)
+
+	// Neither key is over its limit, so the least recently used one, "a",
+	// makes room for "c".
+	assert.True(t, l.Allow("b").Allowed)
+	assert.True(t, l.Allow("c").Allowed)
+	assert.Equal(t, 2, l.Len())
+	assert.False(t, l.Allow("b").Allowed)
+	assert.True(t, l.Allow("a").Allowed)
+	assert.Equal(t, 2, l.Len())
+}
+
+func TestLimiterEvictionKeepsLimitedKeys(t *testing.T) {
+	clock := clocktest.NewManual()
+	for name, l := range map[string]interface {
+		RateLimiter
+		Len() int
+	}{
+		"TokenBucket":   &TokenBucket{limiterConfig: limiterConfig{limit: 2, window: time.Hour, Clock: clock, MaxKeys: 3}},
+		"GCRA":          &GCRA{limiterConfig: limiterConfig{limit: 2, window: time.Hour, Clock: clock, MaxKeys: 3}},
+		"SlidingWindow": &SlidingWindow{limiterConfig: limiterConfig{limit: 2, window: time.Hour, Clock: clock, MaxKeys: 3}},
+	} {
+		assert.True(t, l.AllowN("blocked", 2).Allowed, name)
+		assert.False(t, l.AllowN("blocked", 1).Allowed, name)
+
+		// Keys with a single request are not over their limit, so they are
+		// evicted in turn and never lock out new keys, while the blocked key
+		// stays tracked.
+		for _, key := range []string{"w", "x", "y", "z"} {
+			assert.True(t, l.AllowN(key, 1).Allowed, name)
+		}
+
+		assert.False(t, l.AllowN("blocked", 1).Allowed, name)
+		assert.Equal(t, 3, l.Len(), name)
+
+		// When every key is over its limit the least recently used one goes.
+		for _, key := range []string{"p", "q"} {
+			assert.True(t, l.AllowN(key, 2).Allowed, name)
+		}
+
+		assert.True(t, l.AllowN("r", 1).Allowed, name)
+		assert.True(t, l.AllowN("blocked", 1).Allowed, name)
+		clock.Advance(24 * time.Hour)
+	}
+}
+
+func TestGCRAShortWindow(t *testing.T) {
+	clock := clocktest.NewManual()
+	l := NewGCRA(2000, time.Microsecond)
+	l.Clock = clock
+
+	result := l.AllowN("a", 2000)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.False(t, l.Allow("a").Allowed)
+
+	clock.Advance(time.Nanosecond)
+	result = l.Allow("a")
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	clock := clocktest.NewManual()
+	l := NewGCRA(2, time.Minute)
+	l.Clock = clock
+
+	handler := RateLimitMiddleware(l, func(r *http.Request) string { return r.Header.Get("X-User") })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }))
+
+	serve := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("alice")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rec.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", rec.Header().Get("RateLimit-Policy"))
+
+	assert.Equal(t, http.StatusNoContent, serve("alice").Code)
+	rec = serve("alice")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = serve("")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+}
+
+func TestClientIPKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.10:1234"
+	assert.Equal(t, "192.0.2.10", ClientIPKey(nil)(req))
+
+	req.RemoteAddr = "bogus"
+	assert.Equal(t, "", ClientIPKey(nil)(req))
+}
@@ -9,3 +9,11 @@ type Reloadable interface {
 	// Implementations should handle errors gracefully and maintain consistency.
 	Reload()
 }
+
+// ReloadFunc adapts an ordinary function to the Reloadable interface.
+type ReloadFunc func()
+
+// Reload calls f.
+func (f ReloadFunc) Reload() {
+	f()
+}
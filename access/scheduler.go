@@ -0,0 +1,201 @@
+package access
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Scheduler reloads a Reloadable periodically.
+//
+// After a successful reload the next one happens after Interval. After a
+// failure, as judged by Manager (a panic or an error from Err), the delay
+// starts at RetryInterval and doubles with every consecutive failure up to
+// MaxRetryInterval. A random delay of up to Jitter is added each time so that
+// a fleet of processes started together does not reload in lockstep.
+//
+// Configure the fields before calling Start.
+type Scheduler struct {
+	r Reloadable
+
+	// Interval is the delay between successful reloads.
+	Interval time.Duration
+
+	// Jitter bounds the random delay added to every wait. Zero disables it.
+	Jitter time.Duration
+
+	// RetryInterval is the delay after the first failure. Zero means Interval.
+	RetryInterval time.Duration
+
+	// MaxRetryInterval caps the backoff. Zero means 16 times RetryInterval.
+	MaxRetryInterval time.Duration
+
+	// Clock is the time source. When nil, SystemClock is used.
+	Clock Clock
+
+	// OnReload, when set, is called after every reload with its error. It may
+	// call Stop.
+	OnReload func(err error)
+
+	// randN returns a random number in [0, n); tests replace it.
+	randN func(n int64) int64
+
+	mu         sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+	failures   int
+	next       time.Time
+	inCallback bool
+}
+
+// NewScheduler creates a stopped Scheduler reloading r every interval. It
+// panics if interval is not positive.
+func NewScheduler(r Reloadable, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		panic("access: non-positive interval for NewScheduler")
+	}
+
+	return &Scheduler{r: r, Interval: interval}
+}
+
+// Start begins reloading in the background, the first time after one delay.
+// Calling Start on a running Scheduler does nothing. It panics if Interval is
+// not positive.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Interval <= 0 {
+		panic("access: non-positive Scheduler interval")
+	}
+
+	if s.stop != nil {
+		return
+	}
+
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go s.run(clockOrSystem(s.Clock), s.stop, s.done)
+}
+
+// Stop stops the Scheduler and waits for a reload in progress to finish. A
+// stopped Scheduler can be started again; its failure count is kept.
+//
+// While OnReload runs the reload itself is over, so Stop does not wait for
+// the background goroutine then; this lets OnReload call Stop. No reload
+// starts after Stop returns either way. Stop must not be called from the
+// Reload method of the scheduled Reloadable.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, done, inCallback := s.stop, s.done, s.inCallback
+	s.stop, s.done = nil, nil
+	s.next = time.Time{}
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	if !inCallback {
+		<-done
+	}
+}
+
+// Running reports whether the Scheduler has been started and not stopped.
+func (s *Scheduler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stop != nil
+}
+
+// Failures returns the number of consecutive failed reloads.
+func (s *Scheduler) Failures() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures
+}
+
+// Next returns when the next reload is due, or the zero time when stopped.
+func (s *Scheduler) Next() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next
+}
+
+func (s *Scheduler) run(clock Clock, stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		s.mu.Lock()
+		delay := s.delay(s.failures)
+		s.next = clock.Now().Add(delay)
+		s.mu.Unlock()
+
+		timer := clock.NewTimer(delay)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		err := reloadComponent(s.r)
+
+		s.mu.Lock()
+		if err != nil {
+			s.failures++
+		} else {
+			s.failures = 0
+		}
+
+		s.inCallback = s.OnReload != nil
+		s.mu.Unlock()
+
+		if s.OnReload != nil {
+			s.OnReload(err)
+			s.mu.Lock()
+			s.inCallback = false
+			s.mu.Unlock()
+		}
+	}
+}
+
+// delay returns the wait after the given number of consecutive failures.
+func (s *Scheduler) delay(failures int) time.Duration {
+	delay := s.Interval
+	if failures > 0 {
+		retry := s.RetryInterval
+		if retry <= 0 {
+			retry = s.Interval
+		}
+
+		limit := s.MaxRetryInterval
+		if limit <= 0 {
+			limit = 16 * retry
+		}
+
+		delay = retry
+		for i := 1; i < failures && delay < limit; i++ {
+			delay *= 2
+		}
+
+		delay = min(delay, limit)
+	}
+
+	if s.Jitter > 0 {
+		randN := s.randN
+		if randN == nil {
+			randN = rand.Int64N
+		}
+
+		delay += time.Duration(randN(int64(s.Jitter)))
+	}
+
+	return delay
+}
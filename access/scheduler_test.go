@@ -0,0 +1,134 @@
+package access
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yetiz-org/goth-util/internal/clock/clocktest"
+)
+
+func TestSchedulerBackoff(t *testing.T) {
+	clock := clocktest.NewManual()
+	results := []error{errors.New("a"), errors.New("b"), errors.New("c"), errors.New("d"), nil}
+	reloads := make(chan error, len(results))
+	s := NewScheduler(&scriptedReloadable{results: results}, time.Minute)
+	s.RetryInterval = 10 * time.Second
+	s.MaxRetryInterval = 30 * time.Second
+	s.Clock = clock
+	s.OnReload = func(err error) { reloads <- err }
+
+	assert.False(t, s.Running())
+	s.Start()
+	s.Start()
+	assert.True(t, s.Running())
+
+	for _, want := range []struct {
+		delay    time.Duration
+		failures int
+	}{
+		{time.Minute, 1},
+		{10 * time.Second, 2},
+		{20 * time.Second, 3},
+		{30 * time.Second, 4},
+		{30 * time.Second, 0},
+		{time.Minute, 0},
+	} {
+		clock.WaitPending(t, 1)
+		assert.Equal(t, clock.Now().Add(want.delay), s.Next())
+
+		clock.Advance(want.delay - time.Nanosecond)
+		assert.Equal(t, 1, clock.Pending())
+		clock.Advance(time.Nanosecond)
+		<-reloads
+		assert.Eventually(t, func() bool { return s.Failures() == want.failures }, time.Second, time.Millisecond)
+	}
+
+	s.Stop()
+	assert.False(t, s.Running())
+	assert.True(t, s.Next().IsZero())
+	assert.Equal(t, 0, clock.Pending())
+	s.Stop()
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	clock := clocktest.NewManual()
+	s := NewScheduler(ReloadFunc(func() {}), time.Minute)
+	s.Jitter = 5 * time.Second
+	s.Clock = clock
+	s.randN = func(n int64) int64 { return n - 1 }
+
+	s.Start()
+	defer s.Stop()
+
+	clock.WaitPending(t, 1)
+	assert.Equal(t, clock.Now().Add(time.Minute+5*time.Second-time.Nanosecond), s.Next())
+}
+
+func TestSchedulerStopFromOnReload(t *testing.T) {
+	clock := clocktest.NewManual()
+	var reloads atomic.Int32
+	s := NewScheduler(ReloadFunc(func() { reloads.Add(1) }), time.Minute)
+	s.Clock = clock
+	stopped := make(chan struct{})
+	s.OnReload = func(error) {
+		s.Stop()
+		close(stopped)
+	}
+
+	s.Start()
+	clock.WaitPending(t, 1)
+	clock.Advance(time.Minute)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop called from OnReload deadlocked")
+	}
+
+	assert.False(t, s.Running())
+	clock.WaitPending(t, 0)
+	assert.Equal(t, int32(1), reloads.Load())
+}
+
+func TestSchedulerInvalidInterval(t *testing.T) {
+	assert.Panics(t, func() { NewScheduler(ReloadFunc(func() {}), 0) })
+
+	s := NewScheduler(ReloadFunc(func() {}), time.Minute)
+	s.Interval = -time.Second
+	assert.Panics(t, s.Start)
+	assert.False(t, s.Running())
+}
+
+func TestSchedulerDelay(t *testing.T) {
+	s := NewScheduler(nil, time.Minute)
+	assert.Equal(t, time.Minute, s.delay(0))
+	assert.Equal(t, time.Minute, s.delay(1))
+	assert.Equal(t, 16*time.Minute, s.delay(100))
+
+	s.Jitter = time.Second
+	for i := 0; i < 100; i++ {
+		delay := s.delay(0)
+		assert.GreaterOrEqual(t, delay, time.Minute)
+		assert.Less(t, delay, time.Minute+time.Second)
+	}
+}
+
+// scriptedReloadable reports the next result from a script on every Reload.
+type scriptedReloadable struct {
+	results []error
+	err     error
+}
+
+func (s *scriptedReloadable) Reload() {
+	s.err = nil
+	if len(s.results) > 0 {
+		s.err, s.results = s.results[0], s.results[1:]
+	}
+}
+
+func (s *scriptedReloadable) Err() error {
+	return s.err
+}
@@ -0,0 +1,190 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Loader produces a fresh value for a Value.
+type Loader[T any] func(ctx context.Context) (T, error)
+
+type valueSnapshot[T any] struct {
+	value    T
+	version  uint64
+	loadedAt time.Time
+}
+
+type subscriber[T any] struct {
+	fn func(old, new T)
+}
+
+// Value holds the current result of a Loader and swaps it atomically on
+// every successful reload. Reads never block, even while a reload runs.
+//
+// When the loader fails the last good value stays in place and the error is
+// available from Err. Each successful load increments Version and notifies
+// subscribers with the previous and the new value.
+//
+// Value implements Reloadable, so it can be registered with a Manager. The
+// zero value holds the zero value of T and has no loader, so only Set
+// changes it; reloading it fails.
+type Value[T any] struct {
+	loader  Loader[T]
+	current atomic.Pointer[valueSnapshot[T]]
+
+	// reloadMu serialises loads so versions and notifications stay ordered.
+	reloadMu sync.Mutex
+	lastErr  atomic.Pointer[error]
+
+	subMu       sync.Mutex
+	subscribers []*subscriber[T]
+}
+
+// NewValue creates a Value holding the zero value of T at version 0. Call
+// Reload or ReloadContext to run the loader for the first time.
+func NewValue[T any](loader Loader[T]) *Value[T] {
+	v := &Value[T]{loader: loader}
+	v.current.Store(&valueSnapshot[T]{})
+	return v
+}
+
+// LoadValue creates a Value and runs the loader once, failing if it fails.
+func LoadValue[T any](ctx context.Context, loader Loader[T]) (*Value[T], error) {
+	v := NewValue(loader)
+	if err := v.ReloadContext(ctx); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// snapshot returns the current snapshot, or an empty one before the first
+// load of a zero Value.
+func (v *Value[T]) snapshot() *valueSnapshot[T] {
+	if s := v.current.Load(); s != nil {
+		return s
+	}
+
+	return &valueSnapshot[T]{}
+}
+
+// Get returns the current value.
+func (v *Value[T]) Get() T {
+	return v.snapshot().value
+}
+
+// Version returns the number of successful loads so far, including Set calls.
+func (v *Value[T]) Version() uint64 {
+	return v.snapshot().version
+}
+
+// LoadedAt returns the time of the last successful load, or the zero time if
+// there has been none.
+func (v *Value[T]) LoadedAt() time.Time {
+	return v.snapshot().loadedAt
+}
+
+// Err returns the error of the most recent load, or nil if it succeeded.
+func (v *Value[T]) Err() error {
+	if err := v.lastErr.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+// Reload implements Reloadable by running the loader with context.Background().
+func (v *Value[T]) Reload() {
+	_ = v.ReloadContext(context.Background())
+}
+
+// ReloadContext runs the loader and, if it succeeds, swaps in the new value
+// and notifies subscribers. A panicking loader counts as a failure.
+func (v *Value[T]) ReloadContext(ctx context.Context) error {
+	v.reloadMu.Lock()
+	defer v.reloadMu.Unlock()
+
+	value, err := v.load(ctx)
+	if err != nil {
+		v.lastErr.Store(&err)
+		return err
+	}
+
+	v.lastErr.Store(nil)
+	v.swap(value)
+	return nil
+}
+
+// Set stores value as if a load had returned it.
+func (v *Value[T]) Set(value T) {
+	v.reloadMu.Lock()
+	defer v.reloadMu.Unlock()
+	v.swap(value)
+}
+
+func (v *Value[T]) load(ctx context.Context) (value T, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return value, err
+	}
+
+	if v.loader == nil {
+		return value, errors.New("access: Value has no loader")
+	}
+
+	return v.loader(ctx)
+}
+
+// swap must be called with reloadMu held.
+func (v *Value[T]) swap(value T) {
+	old := v.snapshot()
+	v.current.Store(&valueSnapshot[T]{value: value, version: old.version + 1, loadedAt: time.Now()})
+
+	v.subMu.Lock()
+	subscribers := v.subscribers
+	v.subMu.Unlock()
+
+	for _, s := range subscribers {
+		s.fn(old.value, value)
+	}
+}
+
+// Subscribe registers fn to be called after every successful load with the
+// previous and the new value. Calls happen synchronously in load order on the
+// reloading goroutine, so fn must not reload v. The returned function removes
+// the subscription.
+func (v *Value[T]) Subscribe(fn func(old, new T)) (unsubscribe func()) {
+	s := &subscriber[T]{fn: fn}
+
+	v.subMu.Lock()
+	v.subscribers = append(append([]*subscriber[T](nil), v.subscribers...), s)
+	v.subMu.Unlock()
+
+	return func() {
+		v.subMu.Lock()
+		defer v.subMu.Unlock()
+
+		kept := make([]*subscriber[T], 0, len(v.subscribers))
+		for _, existing := range v.subscribers {
+			if existing != s {
+				kept = append(kept, existing)
+			}
+		}
+
+		v.subscribers = kept
+	}
+}
+
+// Context returns v as a ReloadableContext.
+func (v *Value[T]) Context() ReloadableContext {
+	return ReloadableContextFunc(v.ReloadContext)
+}
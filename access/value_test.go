@@ -0,0 +1,137 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue(t *testing.T) {
+	next, fail := 1, error(nil)
+	v := NewValue(func(context.Context) (int, error) {
+		if fail != nil {
+			return 0, fail
+		}
+
+		return next, nil
+	})
+
+	assert.Equal(t, 0, v.Get())
+	assert.Equal(t, uint64(0), v.Version())
+	assert.True(t, v.LoadedAt().IsZero())
+
+	var changes [][2]int
+	unsubscribe := v.Subscribe(func(old, new int) { changes = append(changes, [2]int{old, new}) })
+
+	assert.NoError(t, v.ReloadContext(context.Background()))
+	assert.Equal(t, 1, v.Get())
+	assert.Equal(t, uint64(1), v.Version())
+	assert.False(t, v.LoadedAt().IsZero())
+	loadedAt := v.LoadedAt()
+
+	fail = errors.New("source unavailable")
+	v.Reload()
+	assert.Equal(t, fail, v.Err())
+	assert.Equal(t, 1, v.Get())
+	assert.Equal(t, uint64(1), v.Version())
+	assert.Equal(t, loadedAt, v.LoadedAt())
+
+	fail, next = nil, 2
+	v.Reload()
+	assert.NoError(t, v.Err())
+	assert.Equal(t, 2, v.Get())
+	assert.Equal(t, uint64(2), v.Version())
+
+	unsubscribe()
+	v.Set(3)
+	assert.Equal(t, 3, v.Get())
+	assert.Equal(t, uint64(3), v.Version())
+	assert.Equal(t, [][2]int{{0, 1}, {1, 2}}, changes)
+}
+
+func TestValuePanicAndCancel(t *testing.T) {
+	v := NewValue(func(context.Context) (string, error) { panic("boom") })
+	assert.EqualError(t, v.ReloadContext(context.Background()), "panic: boom")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, v.Context().Reload(ctx), context.Canceled)
+	assert.Equal(t, uint64(0), v.Version())
+}
+
+func TestValueZero(t *testing.T) {
+	var v Value[string]
+	assert.Equal(t, "", v.Get())
+	assert.Equal(t, uint64(0), v.Version())
+	assert.True(t, v.LoadedAt().IsZero())
+	assert.EqualError(t, v.ReloadContext(context.Background()), "access: Value has no loader")
+
+	var seen []string
+	v.Subscribe(func(old, new string) { seen = append(seen, old+">"+new) })
+	v.Set("a")
+	assert.Equal(t, "a", v.Get())
+	assert.Equal(t, uint64(1), v.Version())
+	assert.Equal(t, []string{">a"}, seen)
+}
+
+func TestLoadValue(t *testing.T) {
+	v, err := LoadValue(context.Background(), func(context.Context) ([]string, error) { return []string{"a"}, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, v.Get())
+
+	_, err = LoadValue(context.Background(), func(context.Context) ([]string, error) { return nil, errors.New("bad") })
+	assert.EqualError(t, err, "bad")
+}
+
+func TestValueWithManager(t *testing.T) {
+	v := NewValue(func(context.Context) (int, error) { return 0, errors.New("bad") })
+	m := NewManager()
+	assert.NoError(t, m.Register("value", v))
+
+	report, err := m.ReloadNow("test")
+	assert.NoError(t, err)
+	assert.EqualError(t, report.Err(), "value: bad")
+}
+
+func TestValueConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	n := 0
+	v := NewValue(func(context.Context) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return n, nil
+	})
+
+	var last int
+	v.Subscribe(func(old, new int) {
+		assert.Equal(t, last, old)
+		assert.Greater(t, new, old)
+		last = new
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				v.Reload()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = v.Get()
+				_ = v.Version()
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 400, v.Get())
+	assert.Equal(t, uint64(400), v.Version())
+}
@@ -0,0 +1,113 @@
+package access
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ReloadOnSignal calls r.Reload whenever the process receives one of sigs,
+// or SIGHUP when none are given. The returned function stops listening.
+func ReloadOnSignal(r Reloadable, sigs ...os.Signal) (stop func()) {
+	return notifySignals(func(os.Signal) { r.Reload() }, sigs...)
+}
+
+// notifySignals calls fn for every arriving signal of sigs, or SIGHUP when
+// none are given, until the returned function is called.
+func notifySignals(fn func(os.Signal), sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				fn(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// WatchFile polls path every interval and calls r.Reload when its size or
+// modification time changes, or when it appears after being missing. A change
+// is acted on only once the file has looked the same for two polls in a row,
+// so a file that is still being written is not loaded half-way, and an empty
+// file is ignored until it has content. Removing the file does not reload.
+// The returned function stops polling and waits for a running Reload to
+// return; it must not be called from r.Reload. WatchFile panics if interval
+// is not positive.
+func WatchFile(path string, interval time.Duration, r Reloadable) (stop func()) {
+	if interval <= 0 {
+		panic("access: non-positive interval for WatchFile")
+	}
+
+	ticker := time.NewTicker(interval)
+	done, exited := make(chan struct{}), make(chan struct{})
+	last := fileStamp(path)
+	pending := last
+	go func() {
+		defer close(exited)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				current := fileStamp(path)
+				settled := current == pending
+				pending = current
+				if !settled || current == last || !current.exists || current.size == 0 {
+					continue
+				}
+
+				// stop may have raced with the tick; never reload after it.
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				last = current
+				r.Reload()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
+
+// stamp identifies a version of a file by size and modification time.
+type stamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func fileStamp(path string) stamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+
+	return stamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
@@ -0,0 +1,124 @@
+package access
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingReloadable counts Reload calls and is safe for concurrent use.
+type countingReloadable struct {
+	count atomic.Int32
+}
+
+func (c *countingReloadable) Reload() {
+	c.count.Add(1)
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	r := &countingReloadable{}
+	stop := ReloadOnSignal(r, syscall.SIGUSR1)
+	defer stop()
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool { return r.count.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	stop()
+	stop()
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watched")
+	r := &countingReloadable{}
+	stop := WatchFile(path, 5*time.Millisecond, r)
+	defer stop()
+
+	// Appearing counts as a change
+	assert.NoError(t, os.WriteFile(path, []byte("a"), 0o644))
+	assert.Eventually(t, func() bool { return r.count.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(path, []byte("ab"), 0o644))
+	assert.Eventually(t, func() bool { return r.count.Load() == 2 }, time.Second, 5*time.Millisecond)
+
+	// No change, no reload
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(2), r.count.Load())
+
+	// An empty file is not loaded; its content is once written
+	assert.NoError(t, os.WriteFile(path, nil, 0o644))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(2), r.count.Load())
+	assert.NoError(t, os.WriteFile(path, []byte("abc"), 0o644))
+	assert.Eventually(t, func() bool { return r.count.Load() == 3 }, time.Second, 5*time.Millisecond)
+
+	// Removing the file does not reload; recreating it does
+	assert.NoError(t, os.Remove(path))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(3), r.count.Load())
+	assert.NoError(t, os.WriteFile(path, []byte("abcde"), 0o644))
+	assert.Eventually(t, func() bool { return r.count.Load() == 4 }, time.Second, 5*time.Millisecond)
+
+	stop()
+	assert.NoError(t, os.WriteFile(path, []byte("abcd"), 0o644))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(4), r.count.Load())
+
+	assert.PanicsWithValue(t, "access: non-positive interval for WatchFile", func() { WatchFile(path, 0, r) })
+}
+
+func TestWatchFileWaitsForSettledFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watched")
+	r := &countingReloadable{}
+	stop := WatchFile(path, 50*time.Millisecond, r)
+	defer stop()
+
+	// Keep growing the file faster than the poll interval: no reload until
+	// the writer stops.
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	for i := 0; i < 40; i++ {
+		_, err = f.WriteString("line\n")
+		assert.NoError(t, err)
+		assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Duration(i)*time.Second)))
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(t, int32(0), r.count.Load())
+	}
+
+	assert.NoError(t, f.Close())
+	assert.Eventually(t, func() bool { return r.count.Load() == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestWatchFileStopWaits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watched")
+	entered, release := make(chan struct{}), make(chan struct{})
+	var finished atomic.Bool
+	stop := WatchFile(path, time.Millisecond, ReloadFunc(func() {
+		close(entered)
+		<-release
+		finished.Store(true)
+	}))
+
+	assert.NoError(t, os.WriteFile(path, []byte("a"), 0o644))
+	<-entered
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("stop returned while Reload was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-stopped
+	assert.True(t, finished.Load())
+}
@@ -0,0 +1,133 @@
+package kkutil
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrEmptyAddress is returned when the address string is empty.
+	ErrEmptyAddress = errors.New("empty address")
+
+	// ErrInvalidAddress is returned when the host part is neither an IP literal nor a hostname.
+	ErrInvalidAddress = errors.New("invalid address")
+
+	// ErrHostname is returned when the host part is a hostname rather than an IP literal.
+	ErrHostname = errors.New("address is a hostname")
+
+	// ErrMissingPort is returned when the address carries no port.
+	ErrMissingPort = errors.New("missing port")
+
+	// ErrInvalidPort is returned when the port is not a decimal number between 0 and 65535.
+	ErrInvalidPort = errors.New("invalid port")
+)
+
+// AddrError describes a failure to parse a remote address. Host and Port hold
+// the raw components when the address could be split.
+type AddrError struct {
+	Addr string
+	Host string
+	Port string
+	Err  error
+}
+
+func (e *AddrError) Error() string {
+	return "parse address " + strconv.Quote(e.Addr) + ": " + e.Err.Error()
+}
+
+func (e *AddrError) Unwrap() error {
+	return e.Err
+}
+
+// ParseRemoteAddr parses a "host:port" address such as http.Request.RemoteAddr
+// into a netip.AddrPort. IPv6 literals must be bracketed when a port is given
+// and may carry a zone ("[fe80::1%eth0]:80"). IPv4-mapped IPv6 addresses are
+// unmapped, so "[::ffff:10.0.0.1]:80" yields 10.0.0.1:80.
+//
+// Failures are reported as *AddrError wrapping one of the package errors:
+//   - ErrMissingPort when the address is a bare IP; the returned AddrPort
+//     still holds the address with port 0, so callers may accept it.
+//   - ErrHostname when the host is a syntactically valid hostname; the name
+//     is available in AddrError.Host.
+//   - ErrInvalidPort when the port is not a number in the range 0-65535.
+//   - ErrInvalidAddress or ErrEmptyAddress otherwise.
+func ParseRemoteAddr(addr string) (netip.AddrPort, error) {
+	if addr == "" {
+		return netip.AddrPort{}, &AddrError{Addr: addr, Err: ErrEmptyAddress}
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// A bare literal, bracketed or not, is a valid address without a port.
+		bare := addr
+		if len(bare) > 1 && bare[0] == '[' && bare[len(bare)-1] == ']' {
+			bare = bare[1 : len(bare)-1]
+		}
+
+		if ip, perr := netip.ParseAddr(bare); perr == nil {
+			return netip.AddrPortFrom(ip.Unmap(), 0), &AddrError{Addr: addr, Host: bare, Err: ErrMissingPort}
+		}
+
+		if isHostname(addr) {
+			return netip.AddrPort{}, &AddrError{Addr: addr, Host: addr, Err: ErrHostname}
+		}
+
+		return netip.AddrPort{}, &AddrError{Addr: addr, Err: ErrInvalidAddress}
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		e := &AddrError{Addr: addr, Host: host, Port: port, Err: ErrInvalidAddress}
+		if isHostname(host) {
+			e.Err = ErrHostname
+		}
+
+		return netip.AddrPort{}, e
+	}
+
+	// "1.2.3.4:" splits cleanly but carries no port.
+	if port == "" {
+		return netip.AddrPortFrom(ip.Unmap(), 0), &AddrError{Addr: addr, Host: host, Err: ErrMissingPort}
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return netip.AddrPort{}, &AddrError{Addr: addr, Host: host, Port: port, Err: ErrInvalidPort}
+	}
+
+	return netip.AddrPortFrom(ip.Unmap(), uint16(p)), nil
+}
+
+// isHostname reports whether s is a syntactically valid DNS hostname: RFC
+// 1123 labels of letters, digits and hyphens, plus underscores, which service
+// names such as "_sip._tcp" and many internal hosts use. An all-numeric last
+// label is rejected so that malformed IPv4 literals are not mistaken for names.
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+
+	labels := strings.Split(s, ".")
+	if IsInt(labels[len(labels)-1]) {
+		return false
+	}
+
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
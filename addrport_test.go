@@ -0,0 +1,111 @@
+package kkutil
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRemoteAddr(t *testing.T) {
+	cases := map[string]string{
+		"192.168.1.1:8080":        "192.168.1.1:8080",
+		"[2001:db8::1]:443":       "[2001:db8::1]:443",
+		"[::1]:0":                 "[::1]:0",
+		"[fe80::1%eth0]:9000":     "[fe80::1%eth0]:9000",
+		"[::ffff:10.0.0.1]:80":    "10.0.0.1:80",
+		"127.0.0.1:65535":         "127.0.0.1:65535",
+		"[2001:db8::1%25zone]:80": "[2001:db8::1%25zone]:80",
+	}
+
+	for in, expected := range cases {
+		ap, err := ParseRemoteAddr(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, netip.MustParseAddrPort(expected), ap, in)
+	}
+}
+
+func TestParseRemoteAddrErrors(t *testing.T) {
+	cases := map[string]error{
+		"":                     ErrEmptyAddress,
+		"192.168.1.1":          ErrMissingPort,
+		"192.168.1.1:":         ErrMissingPort,
+		"2001:db8::1":          ErrMissingPort,
+		"[2001:db8::1]":        ErrMissingPort,
+		"fe80::1%lo0":          ErrMissingPort,
+		"localhost":            ErrHostname,
+		"localhost:3000":       ErrHostname,
+		"example.com.:80":      ErrHostname,
+		"127.0.0.1:65536":      ErrInvalidPort,
+		"127.0.0.1:-1":         ErrInvalidPort,
+		"127.0.0.1:http":       ErrInvalidPort,
+		"999.1.1.1:80":         ErrInvalidAddress,
+		"[2001:db8::1:8080":    ErrInvalidAddress,
+		":80":                  ErrInvalidAddress,
+		"a b:80":               ErrInvalidAddress,
+		"[10.0.0.1]:80:80":     ErrInvalidAddress,
+		"-bad-.example.com":    ErrInvalidAddress,
+		"[::1]:80]":            ErrInvalidAddress,
+		"1.2.3.4.5":            ErrInvalidAddress,
+		"unknown":              ErrHostname,
+		"_hidden":              ErrHostname,
+		"_sip._tcp.example:80": ErrHostname,
+		"[fe80::1%eth0]:http":  ErrInvalidPort,
+	}
+
+	for in, expected := range cases {
+		_, err := ParseRemoteAddr(in)
+		assert.ErrorIs(t, err, expected, in)
+	}
+
+	// A missing port still yields the address
+	ap, err := ParseRemoteAddr("fe80::1%lo0")
+	assert.ErrorIs(t, err, ErrMissingPort)
+	assert.Equal(t, netip.MustParseAddr("fe80::1%lo0"), ap.Addr())
+
+	// Hostnames are reported separately
+	_, err = ParseRemoteAddr("localhost:3000")
+	var addrErr *AddrError
+	assert.True(t, errors.As(err, &addrErr))
+	assert.Equal(t, "localhost", addrErr.Host)
+	assert.Equal(t, "3000", addrErr.Port)
+	assert.Equal(t, `parse address "localhost:3000": address is a hostname`, err.Error())
+}
+
+func FuzzParseRemoteAddr(f *testing.F) {
+	for _, seed := range []string{
+		"192.168.1.1:8080", "[2001:db8::1]:443", "[fe80::1%eth0]:1", "[::ffff:1.2.3.4]:5",
+		"localhost:80", "1.2.3.4", "::1", "[::1]", ":", "[]:80", "1.2.3.4:99999",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, addr string) {
+		ap, err := ParseRemoteAddr(addr)
+
+		host, port, serr := net.SplitHostPort(addr)
+		var want netip.AddrPort
+		valid := serr == nil
+		if valid {
+			ip, perr := netip.ParseAddr(host)
+			p, uerr := strconv.ParseUint(port, 10, 16)
+			valid = perr == nil && uerr == nil
+			want = netip.AddrPortFrom(ip.Unmap(), uint16(p))
+		}
+
+		if valid != (err == nil) {
+			t.Fatalf("ParseRemoteAddr(%q) err=%v, SplitHostPort valid=%v", addr, err, valid)
+		}
+
+		if valid && ap != want {
+			t.Fatalf("ParseRemoteAddr(%q) = %v, want %v", addr, ap, want)
+		}
+
+		if err != nil && !errors.Is(err, ErrMissingPort) && ap.IsValid() {
+			t.Fatalf("ParseRemoteAddr(%q) returned %v with error %v", addr, ap, err)
+		}
+	})
+}
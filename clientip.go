@@ -0,0 +1,224 @@
+package kkutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/yetiz-org/goth-util/validate"
+)
+
+// Forwarding header names a ClientIPResolver can be configured to read.
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+// ClientIP is the outcome of resolving the originating address of a request.
+type ClientIP struct {
+	// IP is the resolved client address.
+	IP net.IP
+
+	// Proxies lists the trusted hops the request passed through, ordered from
+	// the one nearest the client to the one that connected to this server.
+	// It is empty when the request came directly from the client.
+	Proxies []net.IP
+
+	// Source names where IP was taken from: "RemoteAddr" or one of the header constants.
+	Source string
+}
+
+// ClientIPResolver derives the real client address of an HTTP request that may
+// have passed through reverse proxies. Forwarding headers are only honoured
+// when the connecting peer, and each hop walked back from it, falls inside
+// TrustedProxies; the first untrusted hop is taken to be the client.
+type ClientIPResolver struct {
+	// TrustedProxies lists the networks whose forwarding headers are believed.
+	TrustedProxies []net.IPNet
+
+	// Header is the forwarding header set by the trusted proxies. It is the
+	// only header read; other forwarding headers are client-controlled and
+	// ignored, so a client cannot pick the one that suits it. Empty means
+	// RemoteAddr is always used.
+	Header string
+}
+
+// NewClientIPResolver creates a resolver trusting the given networks and
+// reading X-Forwarded-For. When no networks are given,
+// validate.NonPublicIPNet is trusted, which suits services that sit behind
+// load balancers on private addresses. Set Header when the proxies use
+// Forwarded or X-Real-IP instead.
+func NewClientIPResolver(trusted ...net.IPNet) *ClientIPResolver {
+	if len(trusted) == 0 {
+		trusted = validate.NonPublicIPNet
+	}
+
+	return &ClientIPResolver{
+		TrustedProxies: trusted,
+		Header:         HeaderXForwardedFor,
+	}
+}
+
+// IsTrusted reports whether ip belongs to one of the trusted proxy networks.
+func (r *ClientIPResolver) IsTrusted(ip net.IP) bool {
+	for i := range r.TrustedProxies {
+		if validate.IsIPNetContain(&r.TrustedProxies[i], ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve returns the client address of req. If RemoteAddr cannot be parsed
+// the returned ClientIP has a nil IP.
+func (r *ClientIPResolver) Resolve(req *http.Request) ClientIP {
+	peer := parseHopIP(req.RemoteAddr)
+	result := ClientIP{IP: peer, Source: "RemoteAddr"}
+	if peer == nil || !r.IsTrusted(peer) {
+		return result
+	}
+
+	hops := r.forwardedHops(req)
+	if len(hops) == 0 {
+		return result
+	}
+
+	// Walk from the nearest hop towards the client, collecting trusted proxies
+	// until an untrusted address shows up. A hop that cannot be parsed ends the
+	// walk, since nothing to its left can be verified.
+	chain := []net.IP{peer}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHopIP(hops[i])
+		if ip == nil {
+			break
+		}
+
+		chain = append(chain, ip)
+		if !r.IsTrusted(ip) {
+			break
+		}
+	}
+
+	if len(chain) == 1 {
+		return result
+	}
+
+	last := len(chain) - 1
+	proxies := make([]net.IP, 0, last)
+	for i := last - 1; i >= 0; i-- {
+		proxies = append(proxies, chain[i])
+	}
+
+	return ClientIP{IP: chain[last], Proxies: proxies, Source: r.Header}
+}
+
+// parseHopIP parses a node from RemoteAddr or a forwarding header, with or
+// without a port. It returns nil for hostnames, obfuscated identifiers and
+// anything else that is not an IP literal.
+func parseHopIP(node string) net.IP {
+	ap, err := ParseRemoteAddr(node)
+	if err != nil && !errors.Is(err, ErrMissingPort) {
+		return nil
+	}
+
+	return net.IP(ap.Addr().AsSlice()).To16()
+}
+
+// forwardedHops returns the node list carried by the configured header,
+// ordered from the client towards this server.
+func (r *ClientIPResolver) forwardedHops(req *http.Request) []string {
+	if r.Header == "" {
+		return nil
+	}
+
+	values := req.Header.Values(r.Header)
+	if len(values) == 0 {
+		return nil
+	}
+
+	var hops []string
+	switch http.CanonicalHeaderKey(r.Header) {
+	case HeaderForwarded:
+		for _, value := range values {
+			hops = append(hops, parseForwardedFor(value)...)
+		}
+	case http.CanonicalHeaderKey(HeaderXRealIP):
+		hops = append(hops, strings.TrimSpace(values[len(values)-1]))
+	default:
+		for _, value := range values {
+			for _, hop := range strings.Split(value, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+	}
+
+	return hops
+}
+
+// parseForwardedFor extracts the for= parameters of an RFC 7239 Forwarded
+// header value. Elements without a for= parameter yield an empty node so the
+// walk in Resolve stops there.
+func parseForwardedFor(value string) []string {
+	var nodes []string
+	for _, element := range splitQuoted(value, ',') {
+		node := ""
+		for _, pair := range splitQuoted(element, ';') {
+			key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if found && strings.EqualFold(strings.TrimSpace(key), "for") {
+				node = strings.Trim(strings.TrimSpace(val), `"`)
+			}
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// splitQuoted splits s at sep, ignoring separators inside double quotes.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+type clientIPContextKey struct{}
+
+// Middleware resolves the client address of every request and stores it in the
+// request context, where ClientIPFromContext can retrieve it.
+func (r *ClientIPResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(WithClientIP(req.Context(), r.Resolve(req))))
+	})
+}
+
+// WithClientIP returns a copy of ctx carrying ip.
+func WithClientIP(ctx context.Context, ip ClientIP) context.Context {
+	return context.WithValue(ctx, clientIPContextKey{}, ip)
+}
+
+// ClientIPFromContext returns the ClientIP stored by Middleware or WithClientIP.
+func ClientIPFromContext(ctx context.Context) (ClientIP, bool) {
+	ip, ok := ctx.Value(clientIPContextKey{}).(ClientIP)
+	return ip, ok
+}
@@ -0,0 +1,151 @@
+package kkutil
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yetiz-org/goth-util/validate"
+)
+
+func newRequest(remoteAddr string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Add(k, v)
+	}
+
+	return req
+}
+
+func TestClientIPResolverDirect(t *testing.T) {
+	r := NewClientIPResolver()
+
+	// Untrusted peer: headers are ignored
+	result := r.Resolve(newRequest("93.184.216.34:5555", map[string]string{HeaderXForwardedFor: "1.1.1.1"}))
+	assert.Equal(t, net.ParseIP("93.184.216.34"), result.IP)
+	assert.Empty(t, result.Proxies)
+	assert.Equal(t, "RemoteAddr", result.Source)
+
+	// Trusted peer without forwarding headers
+	result = r.Resolve(newRequest("10.0.0.1:5555", nil))
+	assert.Equal(t, net.ParseIP("10.0.0.1"), result.IP)
+	assert.Empty(t, result.Proxies)
+
+	// Unparseable RemoteAddr
+	result = r.Resolve(newRequest("garbage", nil))
+	assert.Nil(t, result.IP)
+}
+
+func TestClientIPResolverXForwardedFor(t *testing.T) {
+	r := NewClientIPResolver()
+
+	result := r.Resolve(newRequest("10.0.0.1:5555", map[string]string{
+		HeaderXForwardedFor: "8.8.8.8, 1.1.1.1, 192.168.0.5",
+	}))
+	assert.Equal(t, net.ParseIP("1.1.1.1"), result.IP)
+	assert.Equal(t, []net.IP{net.ParseIP("192.168.0.5"), net.ParseIP("10.0.0.1")}, result.Proxies)
+	assert.Equal(t, HeaderXForwardedFor, result.Source)
+
+	// Every hop trusted: the leftmost one is the client
+	result = r.Resolve(newRequest("10.0.0.1:5555", map[string]string{
+		HeaderXForwardedFor: "172.16.0.9, 192.168.0.5",
+	}))
+	assert.Equal(t, net.ParseIP("172.16.0.9"), result.IP)
+	assert.Equal(t, []net.IP{net.ParseIP("192.168.0.5"), net.ParseIP("10.0.0.1")}, result.Proxies)
+
+	// Invalid hop stops the walk at the last verified proxy
+	result = r.Resolve(newRequest("10.0.0.1:5555", map[string]string{
+		HeaderXForwardedFor: "8.8.8.8, unknown, 192.168.0.5",
+	}))
+	assert.Equal(t, net.ParseIP("192.168.0.5"), result.IP)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1")}, result.Proxies)
+
+	// Multiple header lines are concatenated in order
+	req := newRequest("10.0.0.1:5555", nil)
+	req.Header.Add(HeaderXForwardedFor, "9.9.9.9")
+	req.Header.Add(HeaderXForwardedFor, "192.168.0.5")
+	result = r.Resolve(req)
+	assert.Equal(t, net.ParseIP("9.9.9.9"), result.IP)
+}
+
+func TestClientIPResolverForwarded(t *testing.T) {
+	r := NewClientIPResolver()
+	r.Header = HeaderForwarded
+
+	result := r.Resolve(newRequest("[::1]:443", map[string]string{
+		HeaderForwarded:     `for="[2001:db8:cafe::17]:4711";proto=https, for=192.168.0.5;by=10.0.0.1`,
+		HeaderXForwardedFor: "1.1.1.1",
+	}))
+	assert.Equal(t, net.ParseIP("2001:db8:cafe::17"), result.IP)
+	assert.Equal(t, []net.IP{net.ParseIP("192.168.0.5"), net.ParseIP("::1")}, result.Proxies)
+	assert.Equal(t, HeaderForwarded, result.Source)
+
+	// Obfuscated identifiers cannot be verified
+	result = r.Resolve(newRequest("10.0.0.1:80", map[string]string{
+		HeaderForwarded: `for=_hidden, For="192.168.0.5"`,
+	}))
+	assert.Equal(t, net.ParseIP("192.168.0.5"), result.IP)
+
+	assert.Equal(t, []string{"1.2.3.4", "", "[::1]:80"},
+		parseForwardedFor(`for=1.2.3.4;proto="a,b", by=x, FOR="[::1]:80"`))
+}
+
+func TestClientIPResolverXRealIP(t *testing.T) {
+	r := NewClientIPResolver()
+	r.Header = HeaderXRealIP
+	result := r.Resolve(newRequest("10.0.0.1:80", map[string]string{HeaderXRealIP: "8.8.4.4"}))
+	assert.Equal(t, net.ParseIP("8.8.4.4"), result.IP)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1")}, result.Proxies)
+	assert.Equal(t, HeaderXRealIP, result.Source)
+}
+
+func TestClientIPResolverIgnoresOtherHeaders(t *testing.T) {
+	// The proxy appends X-Forwarded-For; a client-supplied Forwarded or
+	// X-Real-IP must not override it.
+	r := NewClientIPResolver()
+	result := r.Resolve(newRequest("10.0.0.5:80", map[string]string{
+		HeaderXForwardedFor: "203.0.113.9",
+		HeaderForwarded:     "for=8.8.8.8",
+		HeaderXRealIP:       "8.8.4.4",
+	}))
+	assert.Equal(t, net.ParseIP("203.0.113.9"), result.IP)
+	assert.Equal(t, HeaderXForwardedFor, result.Source)
+
+	// Without the configured header there is no fallback to another one.
+	result = r.Resolve(newRequest("10.0.0.5:80", map[string]string{HeaderForwarded: "for=8.8.8.8"}))
+	assert.Equal(t, net.ParseIP("10.0.0.5"), result.IP)
+	assert.Equal(t, "RemoteAddr", result.Source)
+
+	r.Header = ""
+	result = r.Resolve(newRequest("10.0.0.5:80", map[string]string{HeaderXForwardedFor: "203.0.113.9"}))
+	assert.Equal(t, net.ParseIP("10.0.0.5"), result.IP)
+}
+
+func TestClientIPResolverCustomTrust(t *testing.T) {
+	r := NewClientIPResolver(*validate.ParseIPNet("203.0.113.0/24"))
+	result := r.Resolve(newRequest("203.0.113.1:80", map[string]string{
+		HeaderXForwardedFor: "10.1.1.1, 203.0.113.7",
+	}))
+	assert.Equal(t, net.ParseIP("10.1.1.1"), result.IP)
+
+	result = r.Resolve(newRequest("10.0.0.1:80", map[string]string{HeaderXForwardedFor: "8.8.8.8"}))
+	assert.Equal(t, net.ParseIP("10.0.0.1"), result.IP)
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	var got ClientIP
+	var found bool
+	handler := NewClientIPResolver().Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got, found = ClientIPFromContext(req.Context())
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("10.0.0.1:80", map[string]string{HeaderXForwardedFor: "8.8.8.8"}))
+	assert.True(t, found)
+	assert.Equal(t, net.ParseIP("8.8.8.8"), got.IP)
+
+	_, found = ClientIPFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context())
+	assert.False(t, found)
+}
@@ -0,0 +1,52 @@
+// Package clock defines the time source shared by the packages of this
+// module, so that tests can drive them with a manual clock instead of
+// sleeping.
+package clock
+
+import "time"
+
+// Clock is a source of time.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the part of *time.Timer used through a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// System is the Clock backed by the time package.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// OrSystem returns c, or System when c is nil.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+
+	return c
+}
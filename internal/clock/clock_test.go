@@ -0,0 +1,24 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedClock struct {
+	Clock
+}
+
+func TestSystem(t *testing.T) {
+	assert.Equal(t, System, OrSystem(nil))
+
+	c := &fixedClock{}
+	assert.Same(t, c, OrSystem(c))
+
+	timer := System.NewTimer(time.Millisecond)
+	<-timer.C()
+	assert.False(t, timer.Stop())
+	assert.WithinDuration(t, time.Now(), System.Now(), time.Second)
+}
@@ -0,0 +1,122 @@
+// Package clocktest provides a manual clock.Clock for tests.
+package clocktest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yetiz-org/goth-util/internal/clock"
+)
+
+// Manual is a Clock whose time only moves when Advance is called.
+type Manual struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	clock    *Manual
+	deadline time.Time
+	c        chan time.Time
+}
+
+// NewManual creates a Manual clock set to 2024-01-01 00:00 UTC.
+func NewManual() *Manual {
+	return &Manual{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the current manual time.
+func (c *Manual) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer that fires once the clock has advanced by d. A
+// non-positive d fires immediately.
+func (c *Manual) NewTimer(d time.Duration) clock.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &manualTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward and fires the timers that became due.
+func (c *Manual) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+
+		t.c <- c.now
+	}
+
+	c.timers = pending
+}
+
+// Pending returns the number of timers that have not fired or been stopped.
+func (c *Manual) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Deadlines returns when the pending timers are due, in creation order.
+func (c *Manual) Deadlines() []time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadlines := make([]time.Time, len(c.timers))
+	for i, t := range c.timers {
+		deadlines[i] = t.deadline
+	}
+
+	return deadlines
+}
+
+// WaitPending waits up to a second until n timers are pending and fails t
+// otherwise.
+func (c *Manual) WaitPending(t testing.TB, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for c.Pending() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("clocktest: %d timers pending, want %d", c.Pending(), n)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
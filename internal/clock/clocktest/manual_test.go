@@ -0,0 +1,31 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManual(t *testing.T) {
+	c := NewManual()
+	start := c.Now()
+
+	immediate := c.NewTimer(0)
+	assert.Equal(t, start, <-immediate.C())
+
+	early, late := c.NewTimer(time.Second), c.NewTimer(time.Minute)
+	assert.Equal(t, 2, c.Pending())
+	assert.Equal(t, []time.Time{start.Add(time.Second), start.Add(time.Minute)}, c.Deadlines())
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-early.C())
+	assert.False(t, early.Stop())
+	c.WaitPending(t, 1)
+
+	assert.True(t, late.Stop())
+	assert.Equal(t, 0, c.Pending())
+	c.Advance(time.Hour)
+	assert.Empty(t, late.C())
+	assert.Equal(t, start.Add(time.Hour+time.Second), c.Now())
+}
@@ -0,0 +1,332 @@
+// Package ssrf provides a dialer and HTTP client that refuse to connect to
+// non-public addresses, for fetching user-supplied URLs such as webhooks and
+// avatars without exposing internal services.
+//
+// Checking a URL's host before the request is not enough: the name may
+// resolve to a different address at connect time (DNS rebinding), and hosts
+// such as "2130706433" or "0x7f.1" are IP literals in disguise. Guard checks
+// every address at the moment of connection, after resolution, so redirects
+// and re-resolved names are covered as well.
+package ssrf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	kkutil "github.com/yetiz-org/goth-util"
+	"github.com/yetiz-org/goth-util/validate"
+)
+
+var (
+	// ErrBlocked is returned when a destination address is not allowed.
+	ErrBlocked = errors.New("destination address is not allowed")
+
+	// ErrNoAddress is returned when a host resolves to no usable address.
+	ErrNoAddress = errors.New("no address for host")
+
+	// ErrScheme is returned when a request or redirect uses a scheme other than http or https.
+	ErrScheme = errors.New("unsupported URL scheme")
+
+	// ErrTooManyRedirects is returned when a request exceeds Guard.MaxRedirects.
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// BlockedError records the host and address a connection was refused for.
+type BlockedError struct {
+	Host string
+	Addr netip.Addr
+}
+
+func (e *BlockedError) Error() string {
+	if e.Host != "" && e.Host != e.Addr.String() {
+		return fmt.Sprintf("ssrf: %s (%s): %v", e.Host, e.Addr, ErrBlocked)
+	}
+
+	return fmt.Sprintf("ssrf: %s: %v", e.Addr, ErrBlocked)
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrBlocked
+}
+
+// Resolver looks up the addresses of a host. *net.Resolver satisfies it; tests
+// can substitute a stub.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// nat64Prefix is the well-known NAT64 prefix, which embeds an IPv4 address.
+var nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// Guard decides which destination addresses may be dialled.
+type Guard struct {
+	// Deny lists the blocked networks. When nil, validate.NonPublicIPSet is used.
+	Deny *validate.IPSet
+
+	// Allow lists exceptions to Deny, such as a known internal API.
+	Allow *validate.IPSet
+
+	// Resolver resolves host names. When nil, net.DefaultResolver is used.
+	Resolver Resolver
+
+	// Dialer is the base dialer. Its Control hook is replaced. When nil, a
+	// dialer with a 30 second timeout and keep-alive is used.
+	Dialer *net.Dialer
+
+	// MaxRedirects bounds the redirects followed by clients from NewClient.
+	// Zero means 10.
+	MaxRedirects int
+}
+
+// NewGuard creates a Guard blocking validate.NonPublicIPNet.
+func NewGuard() *Guard {
+	return &Guard{}
+}
+
+// IsAllowed reports whether addr may be dialled. IPv4-mapped and NAT64
+// addresses are judged by the IPv4 address they carry.
+func (g *Guard) IsAllowed(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+
+	addr = addr.Unmap().WithZone("")
+	if g.Allow != nil && g.Allow.ContainsAddr(addr) {
+		return true
+	}
+
+	deny := g.Deny
+	if deny == nil {
+		deny = validate.NonPublicIPSet()
+	}
+
+	if deny.ContainsAddr(addr) {
+		return false
+	}
+
+	if nat64Prefix.Contains(addr) {
+		b := addr.As16()
+		return g.IsAllowed(netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]}))
+	}
+
+	return true
+}
+
+// Control is a net.Dialer Control hook that refuses connections to addresses
+// the Guard does not allow. It runs after name resolution, immediately
+// before the socket connects, so the address it sees is the one used.
+func (g *Guard) Control(network, address string, _ syscall.RawConn) error {
+	ap, err := kkutil.ParseRemoteAddr(address)
+	if err != nil && !errors.Is(err, kkutil.ErrMissingPort) {
+		return err
+	}
+
+	if !g.IsAllowed(ap.Addr()) {
+		return &BlockedError{Addr: ap.Addr()}
+	}
+
+	return nil
+}
+
+// NewDialer returns a copy of the base dialer with Control set to g.Control.
+func (g *Guard) NewDialer() *net.Dialer {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if g.Dialer != nil {
+		copied := *g.Dialer
+		dialer = &copied
+	}
+
+	dialer.Control = g.Control
+	dialer.ControlContext = nil
+	return dialer
+}
+
+// DialContext resolves address with the Guard's resolver, drops addresses
+// that are not allowed and dials the remaining ones in order. Hosts written
+// as IP literals, including legacy IPv4 forms, are dialled without lookup.
+func (g *Guard) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := g.resolve(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := g.NewDialer()
+	var firstErr error
+	for _, addr := range addrs {
+		if !g.IsAllowed(addr) {
+			if firstErr == nil {
+				firstErr = &BlockedError{Host: host, Addr: addr}
+			}
+
+			continue
+		}
+
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+
+		if firstErr == nil || errors.Is(firstErr, ErrBlocked) {
+			firstErr = err
+		}
+	}
+
+	return nil, firstErr
+}
+
+func (g *Guard) resolve(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	if addr, ok := ParseHostIP(host); ok {
+		return []netip.Addr{addr}, nil
+	}
+
+	var resolver Resolver = net.DefaultResolver
+	if g.Resolver != nil {
+		resolver = g.Resolver
+	}
+
+	lookupNetwork := "ip"
+	switch network {
+	case "tcp4", "udp4":
+		lookupNetwork = "ip4"
+	case "tcp6", "udp6":
+		lookupNetwork = "ip6"
+	}
+
+	addrs, err := resolver.LookupNetIP(ctx, lookupNetwork, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("ssrf: %s: %w", host, ErrNoAddress)
+	}
+
+	return addrs, nil
+}
+
+// NewTransport returns an http.Transport that dials through g. Proxies are
+// disabled, since a proxy would make the connection on the Guard's behalf.
+func (g *Guard) NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = g.DialContext
+	transport.DialTLSContext = nil
+	return transport
+}
+
+// NewClient returns an http.Client using NewTransport. Every request and
+// redirect must use http or https, and redirects are bounded by MaxRedirects.
+// Each redirect target is dialled, and therefore checked, afresh.
+func (g *Guard) NewClient() *http.Client {
+	return &http.Client{
+		Transport:     &schemeCheck{next: g.NewTransport()},
+		CheckRedirect: g.checkRedirect,
+	}
+}
+
+func (g *Guard) checkRedirect(req *http.Request, via []*http.Request) error {
+	limit := g.MaxRedirects
+	if limit == 0 {
+		limit = 10
+	}
+
+	if len(via) >= limit {
+		return ErrTooManyRedirects
+	}
+
+	return checkURL(req.URL)
+}
+
+// schemeCheck rejects non-HTTP URLs before they reach the transport.
+type schemeCheck struct {
+	next http.RoundTripper
+}
+
+func (s *schemeCheck) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := checkURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	return s.next.RoundTrip(req)
+}
+
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("ssrf: %q: %w", u.Scheme, ErrScheme)
+	}
+
+	return nil
+}
+
+// ParseHostIP interprets host as an IP literal. Besides the standard forms it
+// accepts the legacy IPv4 notations understood by inet_aton, which browsers
+// and many HTTP clients still honour: a single 32-bit number ("2130706433"),
+// fewer than four parts ("127.1") and octal or hexadecimal parts ("0177.0.0.1",
+// "0x7f.0.0.1"). Brackets around IPv6 literals are removed.
+func ParseHostIP(host string) (netip.Addr, bool) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap(), true
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return netip.Addr{}, false
+	}
+
+	values := make([]uint64, len(parts))
+	for i, part := range parts {
+		v, ok := parseLegacyPart(part)
+		if !ok {
+			return netip.Addr{}, false
+		}
+
+		values[i] = v
+	}
+
+	// All parts but the last are single bytes; the last fills the remaining bytes.
+	last := values[len(values)-1]
+	if last >= 1<<(8*(5-len(values))) {
+		return netip.Addr{}, false
+	}
+
+	v := last
+	for i, part := range values[:len(values)-1] {
+		if part > 0xff {
+			return netip.Addr{}, false
+		}
+
+		v |= part << (24 - 8*i)
+	}
+
+	return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}), true
+}
+
+func parseLegacyPart(part string) (uint64, bool) {
+	base := 10
+	switch {
+	case part == "":
+		return 0, false
+	case len(part) > 2 && (part[:2] == "0x" || part[:2] == "0X"):
+		part, base = part[2:], 16
+	case len(part) > 1 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+
+	v, err := strconv.ParseUint(part, base, 32)
+	return v, err == nil
+}
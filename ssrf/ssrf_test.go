@@ -0,0 +1,186 @@
+package ssrf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yetiz-org/goth-util/validate"
+)
+
+// stubResolver answers lookups from a fixed table.
+type stubResolver map[string][]netip.Addr
+
+func (r stubResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	if addrs, found := r[host]; found {
+		return addrs, nil
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func newServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, string) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	return server, port
+}
+
+func okHandler(w http.ResponseWriter, _ *http.Request) {
+	_, _ = io.WriteString(w, "ok")
+}
+
+func TestGuardBlocksNonPublic(t *testing.T) {
+	_, port := newServer(t, okHandler)
+	client := NewGuard().NewClient()
+
+	for _, host := range []string{"127.0.0.1", "2130706433", "0177.0.0.1", "0x7f.1", "127.1", "::ffff:127.0.0.1"} {
+		_, err := client.Get("http://" + net.JoinHostPort(host, port) + "/")
+		assert.ErrorIs(t, err, ErrBlocked, host)
+
+		var blocked *BlockedError
+		if assert.True(t, errors.As(err, &blocked), host) {
+			assert.Equal(t, netip.MustParseAddr("127.0.0.1"), blocked.Addr)
+		}
+	}
+}
+
+func TestGuardResolvesAtDialTime(t *testing.T) {
+	_, port := newServer(t, okHandler)
+	guard := &Guard{
+		Resolver: stubResolver{
+			"rebind.test": {netip.MustParseAddr("127.0.0.1")},
+			"mixed.test":  {netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("127.0.0.1")},
+			"empty.test":  {},
+		},
+	}
+
+	client := guard.NewClient()
+	_, err := client.Get("http://rebind.test:" + port + "/")
+	assert.ErrorIs(t, err, ErrBlocked)
+
+	_, err = client.Get("http://mixed.test:" + port + "/")
+	assert.ErrorIs(t, err, ErrBlocked)
+	assert.Contains(t, err.Error(), "mixed.test (10.0.0.1)")
+
+	_, err = client.Get("http://empty.test:" + port + "/")
+	assert.ErrorIs(t, err, ErrNoAddress)
+
+	_, err = client.Get("http://missing.test:" + port + "/")
+	var dnsErr *net.DNSError
+	assert.True(t, errors.As(err, &dnsErr))
+
+	// An explicit exception lets the same name through
+	guard.Allow = validate.NewIPSet(*validate.ParseIPNet("127.0.0.1/32"))
+	resp, err := client.Get("http://rebind.test:" + port + "/")
+	if assert.NoError(t, err) {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "ok", string(body))
+	}
+}
+
+func TestGuardRedirects(t *testing.T) {
+	_, port := newServer(t, okHandler)
+	_, redirectPort := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blocked":
+			http.Redirect(w, r, "http://127.0.0.2:"+port+"/", http.StatusFound)
+		case "/scheme":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/ok":
+			http.Redirect(w, r, "http://127.0.0.1:"+port+"/", http.StatusFound)
+		default:
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		}
+	})
+
+	// Only 127.0.0.2 is denied, so the local test servers are reachable.
+	guard := &Guard{Deny: validate.NewIPSet(*validate.ParseIPNet("127.0.0.2/32")), MaxRedirects: 3}
+	client := guard.NewClient()
+	base := "http://127.0.0.1:" + redirectPort
+
+	resp, err := client.Get(base + "/ok")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	_, err = client.Get(base + "/blocked")
+	assert.ErrorIs(t, err, ErrBlocked)
+
+	_, err = client.Get(base + "/scheme")
+	assert.ErrorIs(t, err, ErrScheme)
+
+	_, err = client.Get(base + "/loop")
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "gopher", Host: "example.com"}, Header: http.Header{}}
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, ErrScheme)
+}
+
+func TestGuardControl(t *testing.T) {
+	server, _ := newServer(t, okHandler)
+
+	// The Control hook alone protects a plain dialer using the system resolver.
+	_, err := NewGuard().NewDialer().Dial("tcp", server.Listener.Addr().String())
+	assert.ErrorIs(t, err, ErrBlocked)
+
+	guard := &Guard{Allow: validate.NewIPSet(*validate.ParseIPNet("127.0.0.0/8"))}
+	conn, err := guard.NewDialer().Dial("tcp", server.Listener.Addr().String())
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+
+	assert.ErrorIs(t, NewGuard().Control("tcp", "[fe80::1%lo]:80", nil), ErrBlocked)
+	assert.NoError(t, NewGuard().Control("tcp", "93.184.216.34:443", nil))
+	assert.Error(t, NewGuard().Control("tcp", "bogus", nil))
+}
+
+func TestGuardIsAllowed(t *testing.T) {
+	guard := NewGuard()
+	assert.True(t, guard.IsAllowed(netip.MustParseAddr("8.8.8.8")))
+	assert.True(t, guard.IsAllowed(netip.MustParseAddr("2606:4700::1111")))
+	assert.True(t, guard.IsAllowed(netip.MustParseAddr("64:ff9b::808:808")))
+	assert.False(t, guard.IsAllowed(netip.MustParseAddr("64:ff9b::7f00:1")))
+	assert.False(t, guard.IsAllowed(netip.MustParseAddr("::ffff:10.0.0.1")))
+	assert.False(t, guard.IsAllowed(netip.MustParseAddr("169.254.169.254")))
+	assert.False(t, guard.IsAllowed(netip.MustParseAddr("fd00::1")))
+	assert.False(t, guard.IsAllowed(netip.Addr{}))
+}
+
+func TestParseHostIP(t *testing.T) {
+	cases := map[string]string{
+		"127.0.0.1":          "127.0.0.1",
+		"2130706433":         "127.0.0.1",
+		"0x7f000001":         "127.0.0.1",
+		"017700000001":       "127.0.0.1",
+		"0177.0.0.1":         "127.0.0.1",
+		"0x7f.0.0.1":         "127.0.0.1",
+		"127.1":              "127.0.0.1",
+		"10.1.258":           "10.1.1.2",
+		"0":                  "0.0.0.0",
+		"[::1]":              "::1",
+		"::ffff:169.254.1.1": "169.254.1.1",
+		"fe80::1%eth0":       "fe80::1%eth0",
+	}
+
+	for in, expected := range cases {
+		addr, ok := ParseHostIP(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, netip.MustParseAddr(expected), addr, in)
+	}
+
+	for _, in := range []string{"", "example.com", "1.2.3.4.5", "256.1.1.1", "1.2.3.256", "4294967296", "08.0.0.1", "0x", "1..1", "1.2.3.", "-1"} {
+		_, ok := ParseHostIP(in)
+		assert.False(t, ok, in)
+	}
+}
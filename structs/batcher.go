@@ -0,0 +1,353 @@
+package structs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed is returned by Push and Flush after Close.
+var ErrBatcherClosed = errors.New("structs: batcher closed")
+
+// BatcherOptions configures a Batcher.
+type BatcherOptions[T any] struct {
+	// MaxSize flushes a batch when it holds this many items. Zero means 100.
+	MaxSize int
+
+	// MaxAge flushes a batch this long after its first item arrived. Zero
+	// disables age-based flushing.
+	MaxAge time.Duration
+
+	// MaxInFlight bounds the batches being flushed at once. When the limit is
+	// reached, Push blocks before completing another batch. Zero means 1.
+	MaxInFlight int
+
+	// MaxRetries is the number of times a failed flush is retried.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubling for every
+	// further one. Zero means 100 milliseconds.
+	RetryBackoff time.Duration
+
+	// OnRetry, when set, is called before a failed batch is retried.
+	OnRetry func(batch []T, attempt int, err error)
+
+	// OnError, when set, is called when a batch fails for good. A panicking
+	// flush function fails its attempt with an error.
+	OnError func(batch []T, err error)
+
+	// Clock is the time source. When nil, SystemClock is used.
+	Clock Clock
+}
+
+// Batcher collects items and hands them to a flush function in batches, when
+// a batch reaches MaxSize items or MaxAge, on Flush and on Close. Flushes run
+// in the background, at most MaxInFlight at a time, which pushes back on
+// producers when the flush function falls behind. Batcher is safe for
+// concurrent use.
+type Batcher[T any] struct {
+	flush func(ctx context.Context, batch []T) error
+	opts  BatcherOptions[T]
+	clock Clock
+
+	// ctx is passed to flush calls and cancelled when Close gives up.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	batch    []T
+	stopAge  chan struct{} // closed when the current batch is taken
+	inFlight int
+	flights  map[chan struct{}]struct{} // closed when their flush finishes
+	closed   bool
+
+	// changed is closed and replaced whenever a flush finishes.
+	changed chan struct{}
+}
+
+// NewBatcher creates a Batcher calling flush with every batch.
+func NewBatcher[T any](flush func(ctx context.Context, batch []T) error, opts BatcherOptions[T]) *Batcher[T] {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 100
+	}
+
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = 1
+	}
+
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 100 * time.Millisecond
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Batcher[T]{
+		flush:   flush,
+		opts:    opts,
+		clock:   clockOrSystem(opts.Clock),
+		ctx:     ctx,
+		cancel:  cancel,
+		flights: map[chan struct{}]struct{}{},
+		changed: make(chan struct{}),
+	}
+}
+
+// Push adds v to the current batch. When v completes the batch and
+// MaxInFlight flushes are running, Push waits for one to finish or for ctx
+// to be done.
+func (b *Batcher[T]) Push(ctx context.Context, v T) error {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return ErrBatcherClosed
+		}
+
+		if len(b.batch)+1 < b.opts.MaxSize || b.inFlight < b.opts.MaxInFlight {
+			b.batch = append(b.batch, v)
+			if len(b.batch) == 1 {
+				b.startAge()
+			}
+
+			if len(b.batch) >= b.opts.MaxSize {
+				b.dispatch()
+			}
+
+			b.mu.Unlock()
+			return nil
+		}
+
+		wait := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wait:
+		}
+	}
+}
+
+// Flush hands the current batch to the flush function and waits until it
+// and the flushes already in progress have finished, or ctx is done. Batches
+// completed by later pushes are not waited for.
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return ErrBatcherClosed
+	}
+
+	return b.drain(ctx)
+}
+
+// Close stops accepting items, flushes the rest and waits for all flushes to
+// finish. If ctx is done first, the context of running flushes is cancelled
+// and ctx.Err() returned. Closing twice waits again.
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	if err := b.drain(ctx); err != nil {
+		b.cancel()
+		return err
+	}
+
+	b.cancel()
+	return nil
+}
+
+// drain dispatches the current batch and waits for it and for the flushes
+// running at the time.
+func (b *Batcher[T]) drain(ctx context.Context) error {
+	b.mu.Lock()
+	batch := b.take()
+	waits := make([]chan struct{}, 0, len(b.flights)+1)
+	for done := range b.flights {
+		waits = append(waits, done)
+	}
+
+	for len(batch) > 0 {
+		if b.inFlight < b.opts.MaxInFlight {
+			waits = append(waits, b.start(batch))
+			break
+		}
+
+		wait := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.putBack(batch)
+			b.mu.Unlock()
+			return ctx.Err()
+		case <-wait:
+		}
+
+		b.mu.Lock()
+	}
+	b.mu.Unlock()
+
+	for _, done := range waits {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+		}
+	}
+
+	return nil
+}
+
+// Pending returns the number of items in the current batch.
+func (b *Batcher[T]) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.batch)
+}
+
+// InFlight returns the number of batches being flushed.
+func (b *Batcher[T]) InFlight() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.inFlight
+}
+
+// startAge arms the MaxAge timer for a new batch. It must be called with mu held.
+func (b *Batcher[T]) startAge() {
+	if b.opts.MaxAge <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	b.stopAge = stop
+	timer := b.clock.NewTimer(b.opts.MaxAge)
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-stop:
+			return
+		case <-timer.C():
+		}
+
+		for {
+			b.mu.Lock()
+			if b.stopAge != stop {
+				b.mu.Unlock()
+				return
+			}
+
+			if b.inFlight < b.opts.MaxInFlight {
+				b.dispatch()
+				b.mu.Unlock()
+				return
+			}
+
+			wait := b.changed
+			b.mu.Unlock()
+
+			select {
+			case <-stop:
+				return
+			case <-wait:
+			}
+		}
+	}()
+}
+
+// dispatch starts flushing the current batch. It must be called with mu held
+// and a free in-flight slot.
+func (b *Batcher[T]) dispatch() {
+	b.start(b.take())
+}
+
+// take removes the current batch and stops its MaxAge timer. It must be
+// called with mu held.
+func (b *Batcher[T]) take() []T {
+	batch := b.batch
+	b.batch = nil
+	if b.stopAge != nil {
+		close(b.stopAge)
+		b.stopAge = nil
+	}
+
+	return batch
+}
+
+// putBack returns a taken batch ahead of the items pushed since. It must be
+// called with mu held.
+func (b *Batcher[T]) putBack(batch []T) {
+	if len(b.batch) == 0 {
+		b.startAge()
+	}
+
+	b.batch = append(batch, b.batch...)
+}
+
+// start flushes batch in the background and returns a channel closed when it
+// is done. It must be called with mu held and a free in-flight slot.
+func (b *Batcher[T]) start(batch []T) chan struct{} {
+	done := make(chan struct{})
+	b.flights[done] = struct{}{}
+	b.inFlight++
+	go func() {
+		b.run(batch)
+
+		b.mu.Lock()
+		b.inFlight--
+		delete(b.flights, done)
+		close(done)
+		close(b.changed)
+		b.changed = make(chan struct{})
+		b.mu.Unlock()
+	}()
+
+	return done
+}
+
+// run flushes batch, retrying as configured.
+func (b *Batcher[T]) run(batch []T) {
+	backoff := b.opts.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := b.call(batch)
+		if err == nil {
+			return
+		}
+
+		if attempt > b.opts.MaxRetries || b.ctx.Err() != nil {
+			if b.opts.OnError != nil {
+				b.opts.OnError(batch, err)
+			}
+
+			return
+		}
+
+		if b.opts.OnRetry != nil {
+			b.opts.OnRetry(batch, attempt, err)
+		}
+
+		timer := b.clock.NewTimer(backoff)
+		select {
+		case <-b.ctx.Done():
+		case <-timer.C():
+		}
+
+		timer.Stop()
+		backoff *= 2
+	}
+}
+
+// call runs the flush function, turning a panic into an error.
+func (b *Batcher[T]) call(batch []T) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return b.flush(b.ctx, batch)
+}
@@ -0,0 +1,234 @@
+package structs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yetiz-org/goth-util/internal/clock/clocktest"
+)
+
+// batchRecorder collects flushed batches.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (r *batchRecorder) flush(_ context.Context, batch []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func (r *batchRecorder) get() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]int(nil), r.batches...)
+}
+
+func TestBatcherSize(t *testing.T) {
+	ctx := context.Background()
+	r := &batchRecorder{}
+	b := NewBatcher(r.flush, BatcherOptions[int]{MaxSize: 3})
+
+	for i := 0; i < 7; i++ {
+		assert.NoError(t, b.Push(ctx, i))
+	}
+
+	assert.NoError(t, b.Flush(ctx))
+	assert.Equal(t, [][]int{{0, 1, 2}, {3, 4, 5}, {6}}, r.get())
+	assert.Equal(t, 0, b.Pending())
+
+	assert.NoError(t, b.Push(ctx, 7))
+	assert.Equal(t, 1, b.Pending())
+	assert.NoError(t, b.Close(ctx))
+	assert.Equal(t, []int{7}, r.get()[3])
+
+	assert.Equal(t, ErrBatcherClosed, b.Push(ctx, 8))
+	assert.Equal(t, ErrBatcherClosed, b.Flush(ctx))
+	assert.NoError(t, b.Close(ctx))
+}
+
+func TestBatcherAge(t *testing.T) {
+	ctx := context.Background()
+	clock := clocktest.NewManual()
+	r := &batchRecorder{}
+	b := NewBatcher(r.flush, BatcherOptions[int]{MaxSize: 10, MaxAge: 2 * time.Second, Clock: clock})
+
+	assert.NoError(t, b.Push(ctx, 1))
+	clock.Advance(time.Second)
+	assert.NoError(t, b.Push(ctx, 2))
+	assert.Equal(t, 1, clock.Pending())
+
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool { return len(r.get()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int{1, 2}, r.get()[0])
+
+	// A batch flushed by size cancels its age timer.
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, b.Push(ctx, i))
+	}
+
+	clock.WaitPending(t, 0)
+	assert.NoError(t, b.Close(ctx))
+	assert.Len(t, r.get(), 2)
+}
+
+func TestBatcherBackpressure(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	started := make(chan []int, 4)
+	b := NewBatcher(func(_ context.Context, batch []int) error {
+		started <- batch
+		<-release
+		return nil
+	}, BatcherOptions[int]{MaxSize: 2, MaxInFlight: 1})
+
+	assert.NoError(t, b.Push(ctx, 1))
+	assert.NoError(t, b.Push(ctx, 2))
+	<-started
+	assert.Equal(t, 1, b.InFlight())
+
+	// The next item fits, but completing the batch has to wait.
+	assert.NoError(t, b.Push(ctx, 3))
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.Push(timeout, 4), context.DeadlineExceeded)
+
+	pushed := make(chan error)
+	go func() { pushed <- b.Push(ctx, 4) }()
+	release <- struct{}{}
+	assert.NoError(t, <-pushed)
+	assert.Equal(t, []int{3, 4}, <-started)
+	close(release)
+	assert.NoError(t, b.Close(ctx))
+}
+
+func TestBatcherRetry(t *testing.T) {
+	ctx := context.Background()
+	clock := clocktest.NewManual()
+	failures := 2
+	var mu sync.Mutex
+	var retries []int
+	var failed [][]int
+	b := NewBatcher(func(_ context.Context, batch []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if batch[0] == 1 && failures > 0 {
+			failures--
+			return errors.New("temporary")
+		}
+
+		if batch[0] == 2 {
+			return errors.New("permanent")
+		}
+
+		return nil
+	}, BatcherOptions[int]{
+		MaxSize:      1,
+		MaxInFlight:  2,
+		MaxRetries:   2,
+		RetryBackoff: time.Second,
+		Clock:        clock,
+		OnRetry: func(_ []int, attempt int, _ error) {
+			mu.Lock()
+			retries = append(retries, attempt)
+			mu.Unlock()
+		},
+		OnError: func(batch []int, err error) {
+			mu.Lock()
+			failed = append(failed, batch)
+			mu.Unlock()
+		},
+	})
+
+	assert.NoError(t, b.Push(ctx, 1))
+	clock.WaitPending(t, 1)
+	clock.Advance(time.Second)
+	clock.WaitPending(t, 1)
+	clock.Advance(2 * time.Second)
+	assert.NoError(t, b.Flush(ctx))
+
+	assert.NoError(t, b.Push(ctx, 2))
+	clock.WaitPending(t, 1)
+	clock.Advance(time.Second)
+	clock.WaitPending(t, 1)
+	clock.Advance(2 * time.Second)
+	assert.NoError(t, b.Close(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{1, 2, 1, 2}, retries)
+	assert.Equal(t, [][]int{{2}}, failed)
+}
+
+func TestBatcherFlushUnderLoad(t *testing.T) {
+	r := &batchRecorder{}
+	b := NewBatcher(func(ctx context.Context, batch []int) error {
+		time.Sleep(time.Millisecond)
+		return r.flush(ctx, batch)
+	}, BatcherOptions[int]{MaxSize: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for i := 0; ctx.Err() == nil; i++ {
+			_ = b.Push(ctx, i)
+		}
+	}()
+
+	// Flush waits only for what was pending when it was called, so steady
+	// pushes cannot hold it up.
+	time.Sleep(5 * time.Millisecond)
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer flushCancel()
+	assert.NoError(t, b.Flush(flushCtx))
+
+	cancel()
+	<-stopped
+	assert.NoError(t, b.Close(context.Background()))
+	assert.NotEmpty(t, r.get())
+}
+
+func TestBatcherFlushPanic(t *testing.T) {
+	var mu sync.Mutex
+	var failed []error
+	b := NewBatcher(func(context.Context, []int) error { panic("boom") }, BatcherOptions[int]{
+		OnError: func(_ []int, err error) {
+			mu.Lock()
+			failed = append(failed, err)
+			mu.Unlock()
+		},
+	})
+
+	assert.NoError(t, b.Push(context.Background(), 1))
+	assert.NoError(t, b.Flush(context.Background()))
+	assert.Equal(t, 0, b.InFlight())
+	assert.NoError(t, b.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, failed, 1)
+	assert.EqualError(t, failed[0], "panic: boom")
+}
+
+func TestBatcherCloseTimeout(t *testing.T) {
+	flushCtx := make(chan context.Context, 1)
+	b := NewBatcher(func(ctx context.Context, batch []int) error {
+		flushCtx <- ctx
+		<-ctx.Done()
+		return ctx.Err()
+	}, BatcherOptions[int]{})
+
+	assert.NoError(t, b.Push(context.Background(), 1))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.Close(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, (<-flushCtx).Err(), context.Canceled)
+	assert.NoError(t, b.Close(context.Background()))
+}
@@ -0,0 +1,224 @@
+package structs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrQueueClosed is returned by Push on a closed queue and by Pop once a
+	// closed queue has been drained.
+	ErrQueueClosed = errors.New("structs: queue closed")
+
+	// ErrQueueFull is returned when a full queue cannot take an element.
+	ErrQueueFull = errors.New("structs: queue full")
+)
+
+// OverflowPolicy decides what Push does when a bounded queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the element being pushed.
+	OverflowDropNewest
+	// OverflowDropOldest discards the element at the front to make room.
+	OverflowDropOldest
+	// OverflowError fails with ErrQueueFull.
+	OverflowError
+)
+
+// String returns the policy name.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// BlockingQueue is a bounded FIFO queue safe for concurrent use by producers
+// and consumers. Pop waits for an element; Push handles a full queue
+// according to its OverflowPolicy. Both give up when their context is done.
+//
+// Close stops further pushes. Consumers keep receiving the elements still
+// queued and then get ErrQueueClosed, which marks the end of the stream.
+type BlockingQueue[T any] struct {
+	mu       sync.Mutex
+	items    *RingQueue[T]
+	capacity int
+	policy   OverflowPolicy
+	closed   bool
+	dropped  uint64
+
+	// changed is closed and replaced whenever elements are added or removed
+	// or the queue is closed, waking every waiter.
+	changed chan struct{}
+}
+
+// NewBlockingQueue creates a queue holding at most capacity elements.
+func NewBlockingQueue[T any](capacity int, policy OverflowPolicy) *BlockingQueue[T] {
+	capacity = max(capacity, 1)
+	return &BlockingQueue[T]{
+		items:    NewRingQueue[T](min(capacity, 1024)),
+		capacity: capacity,
+		policy:   policy,
+		changed:  make(chan struct{}),
+	}
+}
+
+// Push adds v to the back of the queue. With OverflowBlock it waits for room
+// until ctx is done and returns ctx.Err(). Elements discarded by the drop
+// policies are counted by Dropped; Push still returns nil for them.
+func (q *BlockingQueue[T]) Push(ctx context.Context, v T) error {
+	for {
+		q.mu.Lock()
+		wait, err := q.push(v, q.policy)
+		q.mu.Unlock()
+		if wait == nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wait:
+		}
+	}
+}
+
+// TryPush adds v without waiting. With OverflowBlock a full queue yields
+// ErrQueueFull.
+func (q *BlockingQueue[T]) TryPush(v T) error {
+	policy := q.policy
+	if policy == OverflowBlock {
+		policy = OverflowError
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, err := q.push(v, policy)
+	return err
+}
+
+// push must be called with mu held. It returns a channel to wait on when the
+// element could not be added yet.
+func (q *BlockingQueue[T]) push(v T, policy OverflowPolicy) (<-chan struct{}, error) {
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+
+	if q.items.Len() >= q.capacity {
+		switch policy {
+		case OverflowBlock:
+			return q.changed, nil
+		case OverflowDropNewest:
+			q.dropped++
+			return nil, nil
+		case OverflowDropOldest:
+			q.items.Pop()
+			q.dropped++
+		default:
+			return nil, ErrQueueFull
+		}
+	}
+
+	q.items.Push(v)
+	q.broadcast()
+	return nil, nil
+}
+
+// Pop removes and returns the element at the front of the queue, waiting for
+// one until ctx is done. It returns ErrQueueClosed once the queue is closed
+// and empty.
+func (q *BlockingQueue[T]) Pop(ctx context.Context) (T, error) {
+	for {
+		q.mu.Lock()
+		v, ok := q.items.Pop()
+		closed, wait := q.closed, q.changed
+		if ok {
+			q.broadcast()
+		}
+		q.mu.Unlock()
+
+		switch {
+		case ok:
+			return v, nil
+		case closed:
+			return v, ErrQueueClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			return v, ctx.Err()
+		case <-wait:
+		}
+	}
+}
+
+// TryPop removes and returns the element at the front of the queue without
+// waiting. It reports false when the queue is empty.
+func (q *BlockingQueue[T]) TryPop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	v, ok := q.items.Pop()
+	if ok {
+		q.broadcast()
+	}
+
+	return v, ok
+}
+
+// Close stops further pushes and wakes all waiters. Waiting producers get
+// ErrQueueClosed; consumers drain the remaining elements first. Closing twice
+// does nothing.
+func (q *BlockingQueue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed {
+		q.closed = true
+		q.broadcast()
+	}
+}
+
+// Closed reports whether Close has been called.
+func (q *BlockingQueue[T]) Closed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+// Len returns the number of queued elements.
+func (q *BlockingQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
+
+// Cap returns the maximum number of queued elements.
+func (q *BlockingQueue[T]) Cap() int {
+	return q.capacity
+}
+
+// Dropped returns the number of elements discarded by the drop policies.
+func (q *BlockingQueue[T]) Dropped() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// broadcast must be called with mu held.
+func (q *BlockingQueue[T]) broadcast() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
@@ -0,0 +1,151 @@
+package structs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockingQueuePolicies(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		policy  OverflowPolicy
+		name    string
+		err     error
+		want    []int
+		dropped uint64
+	}{
+		{OverflowDropNewest, "drop-newest", nil, []int{1, 2}, 1},
+		{OverflowDropOldest, "drop-oldest", nil, []int{2, 3}, 1},
+		{OverflowError, "error", ErrQueueFull, []int{1, 2}, 0},
+	} {
+		q := NewBlockingQueue[int](2, test.policy)
+		assert.Equal(t, test.name, test.policy.String())
+		assert.NoError(t, q.Push(ctx, 1))
+		assert.NoError(t, q.Push(ctx, 2))
+		assert.Equal(t, test.err, q.Push(ctx, 3), test.name)
+		assert.Equal(t, test.dropped, q.Dropped(), test.name)
+
+		var got []int
+		for v, ok := q.TryPop(); ok; v, ok = q.TryPop() {
+			got = append(got, v)
+		}
+
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestBlockingQueueBlock(t *testing.T) {
+	q := NewBlockingQueue[int](1, OverflowBlock)
+	assert.Equal(t, "block", OverflowBlock.String())
+	assert.Equal(t, 1, q.Cap())
+	assert.NoError(t, q.TryPush(1))
+	assert.Equal(t, ErrQueueFull, q.TryPush(2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Push(ctx, 2), context.DeadlineExceeded)
+
+	pushed := make(chan error)
+	go func() { pushed <- q.Push(context.Background(), 2) }()
+
+	v, err := q.Pop(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	assert.NoError(t, <-pushed)
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestBlockingQueuePopWaits(t *testing.T) {
+	q := NewBlockingQueue[string](4, OverflowBlock)
+	_, ok := q.TryPop()
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := q.Pop(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	popped := make(chan string)
+	go func() {
+		v, _ := q.Pop(context.Background())
+		popped <- v
+	}()
+
+	assert.NoError(t, q.Push(context.Background(), "x"))
+	assert.Equal(t, "x", <-popped)
+}
+
+func TestBlockingQueueClose(t *testing.T) {
+	ctx := context.Background()
+	q := NewBlockingQueue[int](1, OverflowBlock)
+	assert.NoError(t, q.Push(ctx, 1))
+
+	blocked := make(chan error)
+	go func() { blocked <- q.Push(ctx, 2) }()
+
+	q.Close()
+	q.Close()
+	assert.True(t, q.Closed())
+	assert.Equal(t, ErrQueueClosed, <-blocked)
+	assert.Equal(t, ErrQueueClosed, q.TryPush(3))
+
+	v, err := q.Pop(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	_, err = q.Pop(ctx)
+	assert.Equal(t, ErrQueueClosed, err)
+}
+
+func TestBlockingQueueConcurrent(t *testing.T) {
+	const producers, consumers, perProducer = 8, 8, 500
+	ctx := context.Background()
+	q := NewBlockingQueue[int](16, OverflowBlock)
+
+	var produced sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		produced.Add(1)
+		go func(p int) {
+			defer produced.Done()
+			for i := 0; i < perProducer; i++ {
+				assert.NoError(t, q.Push(ctx, p*perProducer+i))
+			}
+		}(p)
+	}
+
+	var mu sync.Mutex
+	var got []int
+	var consumed sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		consumed.Add(1)
+		go func() {
+			defer consumed.Done()
+			for {
+				v, err := q.Pop(ctx)
+				if err != nil {
+					assert.Equal(t, ErrQueueClosed, err)
+					return
+				}
+
+				mu.Lock()
+				got = append(got, v)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	produced.Wait()
+	q.Close()
+	consumed.Wait()
+
+	sort.Ints(got)
+	assert.Len(t, got, producers*perProducer)
+	for i, v := range got {
+		assert.Equal(t, i, v)
+	}
+}
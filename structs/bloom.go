@@ -0,0 +1,434 @@
+package structs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrBloomMismatch is returned when combining filters of different shape.
+var ErrBloomMismatch = errors.New("structs: bloom filter size or hash count differs")
+
+const bloomHeaderSize = 4 + 8 + 4 + 8
+
+var (
+	bloomMagic         = [4]byte{'B', 'L', 'M', '1'}
+	countingBloomMagic = [4]byte{'C', 'B', 'F', '1'}
+)
+
+// BloomParams returns the number of bits and hash functions a Bloom filter
+// needs to hold n items with false-positive rate p.
+func BloomParams(n uint64, p float64) (m uint64, k uint32) {
+	n = max(n, 1)
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	m = uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k = uint32(max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return m, k
+}
+
+// bloomHash returns the two base hashes of data. Positions are derived from
+// them by double hashing, so k hashes cost one pass over data. The hash is
+// FNV-1a finished with mix64 and is fixed, so serialized filters stay valid.
+func bloomHash(data []byte) (h1, h2 uint64) {
+	h := uint64(14695981039346656037)
+	for _, b := range data {
+		h ^= uint64(b)
+		h *= 1099511628211
+	}
+
+	h1 = mix64(h)
+	return h1, mix64(h1) | 1
+}
+
+// BloomFilter is a probabilistic set: Contains never reports false for an
+// added item, and reports true for a missing one at roughly the configured
+// rate. Add, Contains and Union are safe for concurrent use.
+type BloomFilter struct {
+	m     uint64
+	k     uint32
+	words []atomic.Uint64
+	count atomic.Uint64
+}
+
+// NewBloomFilter creates a filter sized for n items at false-positive rate p.
+func NewBloomFilter(n uint64, p float64) *BloomFilter {
+	return NewBloomFilterSize(BloomParams(n, p))
+}
+
+// NewBloomFilterSize creates a filter of m bits using k hash functions.
+func NewBloomFilterSize(m uint64, k uint32) *BloomFilter {
+	m = max(m, 1)
+	return &BloomFilter{m: m, k: max(k, 1), words: make([]atomic.Uint64, (m+63)/64)}
+}
+
+// Add inserts data.
+func (f *BloomFilter) Add(data []byte) {
+	h1, h2 := bloomHash(data)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.words[bit/64].Or(1 << (bit % 64))
+	}
+
+	f.count.Add(1)
+}
+
+// AddString inserts s.
+func (f *BloomFilter) AddString(s string) {
+	f.Add([]byte(s))
+}
+
+// Contains reports whether data may have been added.
+func (f *BloomFilter) Contains(data []byte) bool {
+	h1, h2 := bloomHash(data)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.words[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ContainsString reports whether s may have been added.
+func (f *BloomFilter) ContainsString(s string) bool {
+	return f.Contains([]byte(s))
+}
+
+// Count returns the number of Add calls, including those merged by Union.
+func (f *BloomFilter) Count() uint64 {
+	return f.count.Load()
+}
+
+// Cap returns the number of bits.
+func (f *BloomFilter) Cap() uint64 {
+	return f.m
+}
+
+// Hashes returns the number of hash functions.
+func (f *BloomFilter) Hashes() uint32 {
+	return f.k
+}
+
+// FalsePositiveRate estimates the current false-positive rate from the share
+// of bits set.
+func (f *BloomFilter) FalsePositiveRate() float64 {
+	var set int
+	for i := range f.words {
+		set += bits.OnesCount64(f.words[i].Load())
+	}
+
+	return math.Pow(float64(set)/float64(f.m), float64(f.k))
+}
+
+// Union adds every item of o to f. Both filters must have the same shape.
+func (f *BloomFilter) Union(o *BloomFilter) error {
+	if f.m != o.m || f.k != o.k {
+		return ErrBloomMismatch
+	}
+
+	for i := range f.words {
+		f.words[i].Or(o.words[i].Load())
+	}
+
+	f.count.Add(o.count.Load())
+	return nil
+}
+
+// Clear removes every item.
+func (f *BloomFilter) Clear() {
+	for i := range f.words {
+		f.words[i].Store(0)
+	}
+
+	f.count.Store(0)
+}
+
+// MarshalBinary encodes the filter, its shape and a checksum. Adds that run
+// concurrently may or may not be included.
+func (f *BloomFilter) MarshalBinary() ([]byte, error) {
+	buf := appendBloomHeader(make([]byte, 0, bloomHeaderSize+len(f.words)*8+4), bloomMagic, f.m, f.k, f.count.Load())
+	for i := range f.words {
+		buf = binary.BigEndian.AppendUint64(buf, f.words[i].Load())
+	}
+
+	return binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf, castagnoli)), nil
+}
+
+// UnmarshalBinary replaces f with a filter encoded by MarshalBinary. It must
+// not run concurrently with other methods.
+func (f *BloomFilter) UnmarshalBinary(data []byte) error {
+	payload, m, k, count, err := parseBloomHeader(data, bloomMagic)
+	if err != nil {
+		return err
+	}
+
+	if len(payload)%8 != 0 || uint64(len(payload)/8) != (m-1)/64+1 {
+		return fmt.Errorf("structs: bloom filter payload is %d bytes for %d bits", len(payload), m)
+	}
+
+	f.m, f.k = m, k
+	f.words = make([]atomic.Uint64, len(payload)/8)
+	for i := range f.words {
+		f.words[i].Store(binary.BigEndian.Uint64(payload[i*8:]))
+	}
+
+	f.count.Store(count)
+	return nil
+}
+
+// CountingBloomFilter is a Bloom filter with an 8-bit counter per position,
+// which allows items to be removed. A counter that reaches 255 sticks there,
+// so heavily shared positions never produce false negatives. It is safe for
+// concurrent use.
+type CountingBloomFilter struct {
+	mu       sync.RWMutex
+	m        uint64
+	k        uint32
+	counters []uint8
+	count    uint64
+}
+
+// NewCountingBloomFilter creates a counting filter sized for n items at
+// false-positive rate p.
+func NewCountingBloomFilter(n uint64, p float64) *CountingBloomFilter {
+	return NewCountingBloomFilterSize(BloomParams(n, p))
+}
+
+// NewCountingBloomFilterSize creates a counting filter with m counters using
+// k hash functions.
+func NewCountingBloomFilterSize(m uint64, k uint32) *CountingBloomFilter {
+	m = max(m, 1)
+	return &CountingBloomFilter{m: m, k: max(k, 1), counters: make([]uint8, m)}
+}
+
+// Add inserts data.
+func (f *CountingBloomFilter) Add(data []byte) {
+	h1, h2 := bloomHash(data)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := uint64(0); i < uint64(f.k); i++ {
+		if c := &f.counters[(h1+i*h2)%f.m]; *c < math.MaxUint8 {
+			*c++
+		}
+	}
+
+	f.count++
+}
+
+// AddString inserts s.
+func (f *CountingBloomFilter) AddString(s string) {
+	f.Add([]byte(s))
+}
+
+// Remove deletes data and reports whether it may have been present. Removing
+// an item that was never added can cause false negatives for others.
+func (f *CountingBloomFilter) Remove(data []byte) bool {
+	h1, h2 := bloomHash(data)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.contains(h1, h2) {
+		return false
+	}
+
+	for i := uint64(0); i < uint64(f.k); i++ {
+		if c := &f.counters[(h1+i*h2)%f.m]; *c < math.MaxUint8 {
+			*c--
+		}
+	}
+
+	if f.count > 0 {
+		f.count--
+	}
+
+	return true
+}
+
+// RemoveString deletes s and reports whether it may have been present.
+func (f *CountingBloomFilter) RemoveString(s string) bool {
+	return f.Remove([]byte(s))
+}
+
+// Contains reports whether data may be present.
+func (f *CountingBloomFilter) Contains(data []byte) bool {
+	h1, h2 := bloomHash(data)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.contains(h1, h2)
+}
+
+// ContainsString reports whether s may be present.
+func (f *CountingBloomFilter) ContainsString(s string) bool {
+	return f.Contains([]byte(s))
+}
+
+func (f *CountingBloomFilter) contains(h1, h2 uint64) bool {
+	for i := uint64(0); i < uint64(f.k); i++ {
+		if f.counters[(h1+i*h2)%f.m] == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Count returns the number of items added and not removed.
+func (f *CountingBloomFilter) Count() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.count
+}
+
+// Cap returns the number of counters.
+func (f *CountingBloomFilter) Cap() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.m
+}
+
+// Hashes returns the number of hash functions.
+func (f *CountingBloomFilter) Hashes() uint32 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.k
+}
+
+// FalsePositiveRate estimates the current false-positive rate from the share
+// of non-zero counters.
+func (f *CountingBloomFilter) FalsePositiveRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var set int
+	for _, c := range f.counters {
+		if c != 0 {
+			set++
+		}
+	}
+
+	return math.Pow(float64(set)/float64(f.m), float64(f.k))
+}
+
+// Union adds every item of o to f. Both filters must have the same shape.
+func (f *CountingBloomFilter) Union(o *CountingBloomFilter) error {
+	// Copy o first so that concurrent a.Union(b) and b.Union(a) cannot
+	// deadlock.
+	o = o.clone()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.m != o.m || f.k != o.k {
+		return ErrBloomMismatch
+	}
+
+	for i, c := range o.counters {
+		f.counters[i] = uint8(min(int(f.counters[i])+int(c), math.MaxUint8))
+	}
+
+	f.count += o.count
+	return nil
+}
+
+func (f *CountingBloomFilter) clone() *CountingBloomFilter {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return &CountingBloomFilter{m: f.m, k: f.k, counters: append([]uint8(nil), f.counters...), count: f.count}
+}
+
+// Filter returns a plain BloomFilter holding the same items.
+func (f *CountingBloomFilter) Filter() *BloomFilter {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	b := NewBloomFilterSize(f.m, f.k)
+	for i, c := range f.counters {
+		if c != 0 {
+			b.words[i/64].Or(1 << (i % 64))
+		}
+	}
+
+	b.count.Store(f.count)
+	return b
+}
+
+// Clear removes every item.
+func (f *CountingBloomFilter) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.counters)
+	f.count = 0
+}
+
+// MarshalBinary encodes the filter, its shape and a checksum.
+func (f *CountingBloomFilter) MarshalBinary() ([]byte, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	buf := appendBloomHeader(make([]byte, 0, bloomHeaderSize+len(f.counters)+4), countingBloomMagic, f.m, f.k, f.count)
+	buf = append(buf, f.counters...)
+	return binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf, castagnoli)), nil
+}
+
+// UnmarshalBinary replaces f with a filter encoded by MarshalBinary.
+func (f *CountingBloomFilter) UnmarshalBinary(data []byte) error {
+	payload, m, k, count, err := parseBloomHeader(data, countingBloomMagic)
+	if err != nil {
+		return err
+	}
+
+	if uint64(len(payload)) != m {
+		return fmt.Errorf("structs: counting bloom filter payload is %d bytes for %d counters", len(payload), m)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.m, f.k, f.count = m, k, count
+	f.counters = append([]uint8(nil), payload...)
+	return nil
+}
+
+func appendBloomHeader(buf []byte, magic [4]byte, m uint64, k uint32, count uint64) []byte {
+	buf = append(buf, magic[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, m)
+	buf = binary.BigEndian.AppendUint32(buf, k)
+	return binary.BigEndian.AppendUint64(buf, count)
+}
+
+// parseBloomHeader verifies the magic and checksum of an encoded filter and
+// returns its payload and shape.
+func parseBloomHeader(data []byte, magic [4]byte) (payload []byte, m uint64, k uint32, count uint64, err error) {
+	if len(data) < bloomHeaderSize+4 {
+		return nil, 0, 0, 0, errors.New("structs: bloom filter data too short")
+	}
+
+	if [4]byte(data[:4]) != magic {
+		return nil, 0, 0, 0, fmt.Errorf("structs: bloom filter magic %q, want %q", data[:4], magic[:])
+	}
+
+	body := data[:len(data)-4]
+	if crc32.Checksum(body, castagnoli) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+		return nil, 0, 0, 0, errors.New("structs: bloom filter checksum mismatch")
+	}
+
+	m = binary.BigEndian.Uint64(data[4:12])
+	k = binary.BigEndian.Uint32(data[12:16])
+	count = binary.BigEndian.Uint64(data[16:24])
+	if m == 0 || k == 0 {
+		return nil, 0, 0, 0, fmt.Errorf("structs: invalid bloom filter shape m=%d k=%d", m, k)
+	}
+
+	return body[bloomHeaderSize:], m, k, count, nil
+}
@@ -0,0 +1,194 @@
+package structs
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomParams(t *testing.T) {
+	m, k := BloomParams(1000, 0.01)
+	assert.Equal(t, uint64(9586), m)
+	assert.Equal(t, uint32(7), k)
+
+	m, k = BloomParams(0, 2)
+	assert.Equal(t, uint64(10), m)
+	assert.Equal(t, uint32(7), k)
+}
+
+func TestBloomFilter(t *testing.T) {
+	f := NewBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.AddString(fmt.Sprintf("user-%d@example.com", i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		assert.True(t, f.ContainsString(fmt.Sprintf("user-%d@example.com", i)))
+	}
+
+	var falsePositives int
+	for i := 0; i < 10000; i++ {
+		if f.ContainsString(fmt.Sprintf("other-%d@example.com", i)) {
+			falsePositives++
+		}
+	}
+
+	assert.Less(t, falsePositives, 200)
+	assert.InDelta(t, 0.01, f.FalsePositiveRate(), 0.005)
+	assert.Equal(t, uint64(1000), f.Count())
+
+	f.Clear()
+	assert.False(t, f.ContainsString("user-1@example.com"))
+	assert.Equal(t, 0.0, f.FalsePositiveRate())
+}
+
+func TestBloomFilterUnion(t *testing.T) {
+	a, b := NewBloomFilter(100, 0.01), NewBloomFilter(100, 0.01)
+	a.AddString("a")
+	b.AddString("b")
+
+	assert.NoError(t, a.Union(b))
+	assert.True(t, a.ContainsString("a"))
+	assert.True(t, a.ContainsString("b"))
+	assert.False(t, b.ContainsString("a"))
+	assert.Equal(t, uint64(2), a.Count())
+
+	assert.Equal(t, ErrBloomMismatch, a.Union(NewBloomFilter(1000, 0.01)))
+}
+
+func TestBloomFilterBinary(t *testing.T) {
+	f := NewBloomFilterSize(100, 3)
+	f.Add([]byte("token"))
+
+	data, err := f.MarshalBinary()
+	assert.NoError(t, err)
+
+	var decoded BloomFilter
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.True(t, decoded.Contains([]byte("token")))
+	assert.Equal(t, f.Cap(), decoded.Cap())
+	assert.Equal(t, f.Hashes(), decoded.Hashes())
+	assert.Equal(t, uint64(1), decoded.Count())
+
+	data[30] ^= 1
+	assert.EqualError(t, decoded.UnmarshalBinary(data), "structs: bloom filter checksum mismatch")
+	assert.Error(t, decoded.UnmarshalBinary(data[:10]))
+
+	counting, _ := NewCountingBloomFilterSize(100, 3).MarshalBinary()
+	assert.Error(t, decoded.UnmarshalBinary(counting))
+}
+
+func TestBloomFilterConcurrent(t *testing.T) {
+	f := NewBloomFilter(8000, 0.01)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				f.AddString(fmt.Sprint(g, "-", i))
+				f.ContainsString(fmt.Sprint(i))
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	assert.Equal(t, uint64(8000), f.Count())
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 1000; i++ {
+			assert.True(t, f.ContainsString(fmt.Sprint(g, "-", i)))
+		}
+	}
+}
+
+func TestCountingBloomFilter(t *testing.T) {
+	f := NewCountingBloomFilter(100, 0.01)
+	f.AddString("a")
+	f.AddString("b")
+	f.AddString("b")
+
+	assert.True(t, f.RemoveString("a"))
+	assert.False(t, f.ContainsString("a"))
+	assert.False(t, f.RemoveString("a"))
+
+	assert.True(t, f.RemoveString("b"))
+	assert.True(t, f.ContainsString("b"))
+	assert.Equal(t, uint64(1), f.Count())
+
+	plain := f.Filter()
+	assert.True(t, plain.ContainsString("b"))
+	assert.Equal(t, f.Cap(), plain.Cap())
+
+	f.Clear()
+	assert.False(t, f.ContainsString("b"))
+	assert.Equal(t, 0.0, f.FalsePositiveRate())
+}
+
+func TestCountingBloomFilterSaturation(t *testing.T) {
+	f := NewCountingBloomFilterSize(10, 2)
+	for i := 0; i < 300; i++ {
+		f.AddString("hot")
+	}
+
+	for i := 0; i < 300; i++ {
+		f.RemoveString("hot")
+	}
+
+	// Saturated counters never drop back to zero.
+	assert.True(t, f.ContainsString("hot"))
+}
+
+func TestCountingBloomFilterUnionAndBinary(t *testing.T) {
+	a, b := NewCountingBloomFilter(100, 0.01), NewCountingBloomFilter(100, 0.01)
+	a.AddString("a")
+	b.AddString("b")
+	assert.NoError(t, a.Union(b))
+	assert.NoError(t, a.Union(a))
+	assert.Equal(t, uint64(4), a.Count())
+	assert.Equal(t, ErrBloomMismatch, a.Union(NewCountingBloomFilterSize(10, 1)))
+
+	data, err := a.MarshalBinary()
+	assert.NoError(t, err)
+
+	var decoded CountingBloomFilter
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.True(t, decoded.RemoveString("b"))
+	assert.True(t, decoded.RemoveString("b"))
+	assert.False(t, decoded.ContainsString("b"))
+	assert.True(t, decoded.ContainsString("a"))
+
+	plain, _ := NewBloomFilterSize(100, 3).MarshalBinary()
+	assert.Error(t, decoded.UnmarshalBinary(plain))
+}
+
+func TestCountingBloomFilterConcurrent(t *testing.T) {
+	f := NewCountingBloomFilter(1000, 0.01)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				f.AddString(fmt.Sprint(g, "-", i))
+				f.ContainsString(fmt.Sprint(i))
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	assert.Equal(t, uint64(1000), f.Count())
+}
+
+func BenchmarkBloomFilterAdd(b *testing.B) {
+	f := NewBloomFilter(uint64(b.N), 0.01)
+	data := []byte("user@example.com")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Add(data)
+	}
+}
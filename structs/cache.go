@@ -0,0 +1,597 @@
+package structs
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EvictionPolicy chooses which entry a full Cache removes.
+type EvictionPolicy int
+
+const (
+	// LRU evicts the least recently used entry.
+	LRU EvictionPolicy = iota
+	// LFU evicts the least frequently used entry, the least recently used
+	// among equally frequent ones.
+	LFU
+)
+
+// EvictionReason tells an OnEvict callback why an entry left the Cache.
+type EvictionReason int
+
+const (
+	// EvictedCapacity means the entry made room for others.
+	EvictedCapacity EvictionReason = iota
+	// EvictedExpired means the entry's TTL passed.
+	EvictedExpired
+	// EvictedDeleted means the entry was removed by Delete or Clear.
+	EvictedDeleted
+)
+
+// String returns the reason name.
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictedCapacity:
+		return "capacity"
+	case EvictedExpired:
+		return "expired"
+	case EvictedDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// CacheOptions configures a Cache. The entry and cost limits apply to the
+// whole cache; when it is full the policy picks a victim in the shard of the
+// key being stored, or in the next shard that holds entries.
+type CacheOptions[K comparable, V any] struct {
+	Policy EvictionPolicy
+
+	// MaxEntries bounds the number of entries. Zero means no limit.
+	MaxEntries int
+
+	// MaxCost bounds the total cost of the entries. Zero means no limit.
+	MaxCost int64
+
+	// Cost returns the cost of an entry. When nil every entry costs 1.
+	Cost func(key K, value V) int64
+
+	// TTL is the lifetime of entries stored with Set. Zero means forever.
+	TTL time.Duration
+
+	// Shards is the number of independently locked shards, rounded up to a
+	// power of two. Zero means 16.
+	Shards int
+
+	// Hash maps keys to shards. It may be nil when K is string, int, int32,
+	// int64, uint, uint32 or uint64; NewCache panics without it for other
+	// key types.
+	Hash func(key K) uint64
+
+	// OnEvict, when set, is called for every entry removed for a reason
+	// other than being overwritten. It runs without locks held.
+	OnEvict func(key K, value V, reason EvictionReason)
+
+	// Clock is the time source for TTLs. When nil, SystemClock is used.
+	Clock Clock
+}
+
+// CacheStats counts Cache lookups and evictions.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// HitRatio returns the fraction of lookups that hit, or 0 without lookups.
+func (s CacheStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+
+	return 0
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	cost    int64
+	expires time.Time
+
+	// LRU list links.
+	prev, next *cacheEntry[K, V]
+
+	// LFU heap handle, use count and last use.
+	item *PriorityItem[*cacheEntry[K, V]]
+	freq uint64
+	used uint64
+}
+
+// cacheUsage counts the entries and cost of all shards, including room
+// reserved for entries about to be stored.
+type cacheUsage struct {
+	entries atomic.Int64
+	cost    atomic.Int64
+}
+
+type cacheShard[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*cacheEntry[K, V]
+	cost    int64
+	usage   *cacheUsage
+
+	lru  cacheEntry[K, V] // sentinel; lru.next is the most recently used
+	lfu  *PriorityQueue[*cacheEntry[K, V]]
+	tick uint64
+}
+
+// LoadPanicError is the value Cache.GetOrLoad panics with when its load
+// function panics.
+type LoadPanicError struct {
+	// Value is the value load panicked with.
+	Value any
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error returns the panic value followed by the stack trace.
+func (e *LoadPanicError) Error() string {
+	return fmt.Sprintf("structs: cache load panicked: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns Value if it is an error.
+func (e *LoadPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+type cacheCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Cache is a sharded in-memory cache with LRU or LFU eviction, per-entry
+// TTLs, entry and cost limits and hit statistics. Expired entries are removed
+// when they are looked up, when the policy picks them for eviction, and by
+// DeleteExpired. Cache is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	opts   CacheOptions[K, V]
+	clock  Clock
+	seed   maphash.Seed
+	shards []*cacheShard[K, V]
+	usage  cacheUsage
+
+	hits, misses, evictions atomic.Uint64
+
+	callsMu sync.Mutex
+	calls   map[K]*cacheCall[V]
+}
+
+// NewCache creates an empty Cache.
+func NewCache[K comparable, V any](opts CacheOptions[K, V]) *Cache[K, V] {
+	if opts.Hash == nil {
+		switch any(*new(K)).(type) {
+		case string, int, int64, int32, uint, uint64, uint32:
+		default:
+			panic(fmt.Sprintf("structs: NewCache needs CacheOptions.Hash for key type %s", reflect.TypeFor[K]()))
+		}
+	}
+
+	shards := 1
+	for shards < opts.Shards || (opts.Shards <= 0 && shards < 16) {
+		shards <<= 1
+	}
+
+	c := &Cache[K, V]{
+		opts:   opts,
+		clock:  clockOrSystem(opts.Clock),
+		seed:   maphash.MakeSeed(),
+		shards: make([]*cacheShard[K, V], shards),
+		calls:  map[K]*cacheCall[V]{},
+	}
+
+	for i := range c.shards {
+		s := &cacheShard[K, V]{entries: map[K]*cacheEntry[K, V]{}, usage: &c.usage}
+		s.lru.prev, s.lru.next = &s.lru, &s.lru
+		s.lfu = NewPriorityQueue(func(a, b *cacheEntry[K, V]) bool {
+			if a.freq != b.freq {
+				return a.freq < b.freq
+			}
+
+			return a.used < b.used
+		})
+
+		c.shards[i] = s
+	}
+
+	return c
+}
+
+func (c *Cache[K, V]) shard(key K) *cacheShard[K, V] {
+	return c.shards[c.shardIndex(key)]
+}
+
+func (c *Cache[K, V]) shardIndex(key K) int {
+	return int(c.hash(key) & uint64(len(c.shards)-1))
+}
+
+func (c *Cache[K, V]) hash(key K) uint64 {
+	if c.opts.Hash != nil {
+		return c.opts.Hash(key)
+	}
+
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(c.seed, k)
+	case int:
+		return mix64(uint64(k))
+	case int64:
+		return mix64(uint64(k))
+	case int32:
+		return mix64(uint64(k))
+	case uint:
+		return mix64(uint64(k))
+	case uint64:
+		return mix64(k)
+	case uint32:
+		return mix64(uint64(k))
+	default:
+		panic("structs: unreachable: NewCache checks the key type")
+	}
+}
+
+// mix64 spreads the bits of an integer key (splitmix64 finaliser).
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// Get returns the value stored for key.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	s := c.shard(key)
+	now := c.clock.Now()
+
+	s.mu.Lock()
+	e, found := s.entries[key]
+	if found && e.expired(now) {
+		s.remove(e)
+		s.mu.Unlock()
+		c.misses.Add(1)
+		c.evicted([]*cacheEntry[K, V]{e}, EvictedExpired)
+		var zero V
+		return zero, false
+	}
+
+	if !found {
+		s.mu.Unlock()
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+
+	s.touch(c.opts.Policy, e)
+	value := e.value
+	s.mu.Unlock()
+
+	c.hits.Add(1)
+	return value, true
+}
+
+// Set stores value for key with the default TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores value for key, expiring after ttl; zero means never.
+// Storing may evict other entries, or the new one if it alone exceeds the
+// cost limit.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	cost := int64(1)
+	if c.opts.Cost != nil {
+		cost = c.opts.Cost(key, value)
+	}
+
+	var expires time.Time
+	now := c.clock.Now()
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+
+	i := c.shardIndex(key)
+	s := c.shards[i]
+	s.mu.Lock()
+	e, found := s.entries[key]
+	if !found {
+		s.mu.Unlock()
+
+		// Reserve room for the new entry and make it first, so that the
+		// limits hold for concurrent readers and a new entry, which is the
+		// least frequently used under LFU, does not evict itself.
+		c.usage.entries.Add(1)
+		c.usage.cost.Add(cost)
+		expired, evicted := c.shrink(i, now)
+		c.evicted(expired, EvictedExpired)
+		c.evicted(evicted, EvictedCapacity)
+
+		s.mu.Lock()
+		e, found = s.entries[key]
+		if found {
+			// Stored concurrently; overwrite it and give the room back.
+			c.usage.entries.Add(-1)
+			c.usage.cost.Add(-cost)
+		} else {
+			e = &cacheEntry[K, V]{key: key, value: value, cost: cost, expires: expires}
+			s.entries[key] = e
+			s.cost += cost
+			s.insert(c.opts.Policy, e)
+		}
+	}
+
+	if found {
+		s.cost += cost - e.cost
+		c.usage.cost.Add(cost - e.cost)
+		e.value, e.cost, e.expires = value, cost, expires
+		s.touch(c.opts.Policy, e)
+	}
+	s.mu.Unlock()
+
+	expired, evicted := c.shrink(i, now)
+	c.evicted(expired, EvictedExpired)
+	c.evicted(evicted, EvictedCapacity)
+}
+
+// Delete removes key, reporting whether it was present.
+func (c *Cache[K, V]) Delete(key K) bool {
+	s := c.shard(key)
+	s.mu.Lock()
+	e, found := s.entries[key]
+	if found {
+		s.remove(e)
+	}
+	s.mu.Unlock()
+
+	if found {
+		c.evicted([]*cacheEntry[K, V]{e}, EvictedDeleted)
+	}
+
+	return found
+}
+
+// Len returns the number of entries, including expired ones not yet removed.
+func (c *Cache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += len(s.entries)
+		s.mu.Unlock()
+	}
+
+	return n
+}
+
+// Cost returns the total cost of the entries.
+func (c *Cache[K, V]) Cost() int64 {
+	var cost int64
+	for _, s := range c.shards {
+		s.mu.Lock()
+		cost += s.cost
+		s.mu.Unlock()
+	}
+
+	return cost
+}
+
+// Clear removes every entry.
+func (c *Cache[K, V]) Clear() {
+	for _, s := range c.shards {
+		s.mu.Lock()
+		removed := make([]*cacheEntry[K, V], 0, len(s.entries))
+		for _, e := range s.entries {
+			s.remove(e)
+			removed = append(removed, e)
+		}
+		s.mu.Unlock()
+
+		c.evicted(removed, EvictedDeleted)
+	}
+}
+
+// DeleteExpired removes every expired entry and returns how many there were.
+func (c *Cache[K, V]) DeleteExpired() int {
+	now := c.clock.Now()
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		var expired []*cacheEntry[K, V]
+		for _, e := range s.entries {
+			if e.expired(now) {
+				s.remove(e)
+				expired = append(expired, e)
+			}
+		}
+		s.mu.Unlock()
+
+		n += len(expired)
+		c.evicted(expired, EvictedExpired)
+	}
+
+	return n
+}
+
+// Stats returns the lookup and eviction counters.
+func (c *Cache[K, V]) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Evictions: c.evictions.Load()}
+}
+
+// GetOrLoad returns the value for key, calling load on a miss and storing its
+// result. Concurrent misses for the same key share one load call; callers
+// that join a running load stop waiting when their ctx is done. Errors are
+// returned to every waiting caller and not cached. If load panics, the
+// waiting callers get an error and the caller that ran load panics with a
+// *LoadPanicError.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context, key K) (V, error)) (V, error) {
+	if value, found := c.Get(key); found {
+		return value, nil
+	}
+
+	c.callsMu.Lock()
+	if call, found := c.calls[key]; found {
+		c.callsMu.Unlock()
+		select {
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		case <-call.done:
+			return call.value, call.err
+		}
+	}
+
+	call := &cacheCall[V]{done: make(chan struct{})}
+	c.calls[key] = call
+	c.callsMu.Unlock()
+
+	defer func() {
+		// A panicking load fails the waiters and then keeps unwinding, with
+		// the stack of the panic, which re-panicking would lose.
+		p := recover()
+		if p != nil {
+			call.err = fmt.Errorf("panic: %v", p)
+		}
+
+		c.callsMu.Lock()
+		delete(c.calls, key)
+		c.callsMu.Unlock()
+		close(call.done)
+
+		if p != nil {
+			panic(&LoadPanicError{Value: p, Stack: debug.Stack()})
+		}
+	}()
+
+	call.value, call.err = load(ctx, key)
+	if call.err == nil {
+		c.Set(key, call.value)
+	}
+
+	return call.value, call.err
+}
+
+// over reports whether the cache exceeds its entry or cost limit.
+func (c *Cache[K, V]) over() bool {
+	return (c.opts.MaxEntries > 0 && c.usage.entries.Load() > int64(c.opts.MaxEntries)) ||
+		(c.opts.MaxCost > 0 && c.usage.cost.Load() > c.opts.MaxCost)
+}
+
+// shrink removes victims, starting in the shard at index first and moving
+// on to the next one when a shard is empty, until the cache is within its
+// limits or has nothing left to evict.
+func (c *Cache[K, V]) shrink(first int, now time.Time) (expired, evicted []*cacheEntry[K, V]) {
+	for i := 0; i < len(c.shards) && c.over(); {
+		s := c.shards[(first+i)&(len(c.shards)-1)]
+		s.mu.Lock()
+		e := s.victim(c.opts.Policy)
+		if e != nil {
+			s.remove(e)
+		}
+		s.mu.Unlock()
+
+		switch {
+		case e == nil:
+			i++
+		case e.expired(now):
+			expired = append(expired, e)
+		default:
+			evicted = append(evicted, e)
+		}
+	}
+
+	return expired, evicted
+}
+
+func (c *Cache[K, V]) evicted(entries []*cacheEntry[K, V], reason EvictionReason) {
+	if reason != EvictedDeleted {
+		c.evictions.Add(uint64(len(entries)))
+	}
+
+	if c.opts.OnEvict == nil {
+		return
+	}
+
+	for _, e := range entries {
+		c.opts.OnEvict(e.key, e.value, reason)
+	}
+}
+
+func (e *cacheEntry[K, V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// insert adds a new entry to the eviction order. Callers hold mu.
+func (s *cacheShard[K, V]) insert(policy EvictionPolicy, e *cacheEntry[K, V]) {
+	s.tick++
+	e.used, e.freq = s.tick, 1
+	if policy == LFU {
+		e.item = s.lfu.PushItem(e)
+		return
+	}
+
+	e.prev, e.next = &s.lru, s.lru.next
+	e.prev.next, e.next.prev = e, e
+}
+
+// touch records a use of e. Callers hold mu.
+func (s *cacheShard[K, V]) touch(policy EvictionPolicy, e *cacheEntry[K, V]) {
+	s.tick++
+	e.used = s.tick
+	e.freq++
+	if policy == LFU {
+		s.lfu.Update(e.item, e)
+		return
+	}
+
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = &s.lru, s.lru.next
+	e.prev.next, e.next.prev = e, e
+}
+
+// remove drops e from the shard. Callers hold mu.
+func (s *cacheShard[K, V]) remove(e *cacheEntry[K, V]) {
+	delete(s.entries, e.key)
+	s.cost -= e.cost
+	s.usage.entries.Add(-1)
+	s.usage.cost.Add(-e.cost)
+	if e.item != nil {
+		s.lfu.Remove(e.item)
+		e.item = nil
+		return
+	}
+
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = nil, nil
+}
+
+// victim returns the entry to evict next. Callers hold mu.
+func (s *cacheShard[K, V]) victim(policy EvictionPolicy) *cacheEntry[K, V] {
+	if policy == LFU {
+		e, _ := s.lfu.Peek()
+		return e
+	}
+
+	if s.lru.prev == &s.lru {
+		return nil
+	}
+
+	return s.lru.prev
+}
@@ -0,0 +1,307 @@
+package structs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yetiz-org/goth-util/internal/clock/clocktest"
+)
+
+type evictionLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *evictionLog) record(key string, value int, reason EvictionReason) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, fmt.Sprintf("%s=%d:%s", key, value, reason))
+}
+
+func (l *evictionLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+func TestCacheLRU(t *testing.T) {
+	log := &evictionLog{}
+	c := NewCache(CacheOptions[string, int]{MaxEntries: 2, Shards: 1, OnEvict: log.record})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, found := c.Get("a")
+	assert.True(t, found)
+
+	c.Set("c", 3)
+	_, found = c.Get("b")
+	assert.False(t, found)
+	assert.Equal(t, []string{"b=2:capacity"}, log.get())
+
+	c.Set("a", 10)
+	v, _ := c.Get("a")
+	assert.Equal(t, 10, v)
+	assert.Equal(t, 2, c.Len())
+
+	assert.True(t, c.Delete("c"))
+	assert.False(t, c.Delete("c"))
+	assert.Equal(t, []string{"b=2:capacity", "c=3:deleted"}, log.get())
+
+	stats := c.Stats()
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Evictions: 1}, stats)
+	assert.InDelta(t, 2.0/3, stats.HitRatio(), 1e-9)
+	assert.Equal(t, 0.0, CacheStats{}.HitRatio())
+}
+
+func TestCacheLFU(t *testing.T) {
+	c := NewCache(CacheOptions[string, int]{Policy: LFU, MaxEntries: 3, Shards: 1})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	for i := 0; i < 3; i++ {
+		c.Get("a")
+		c.Get("c")
+	}
+
+	c.Get("b")
+	c.Set("d", 4)
+
+	// "d" is the only entry used once, so it goes first.
+	c.Set("e", 5)
+	_, found := c.Get("d")
+	assert.False(t, found)
+
+	for _, key := range []string{"a", "b", "c", "e"} {
+		_, found := c.Get(key)
+		assert.Equal(t, key != "b", found, key)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	clock := clocktest.NewManual()
+	log := &evictionLog{}
+	c := NewCache(CacheOptions[string, int]{TTL: time.Minute, Clock: clock, OnEvict: log.record})
+
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Hour)
+	c.SetWithTTL("c", 3, 0)
+
+	clock.Advance(time.Minute)
+	_, found := c.Get("a")
+	assert.False(t, found)
+	_, found = c.Get("b")
+	assert.True(t, found)
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, 1, c.DeleteExpired())
+	assert.Equal(t, 1, c.Len())
+	_, found = c.Get("c")
+	assert.True(t, found)
+	assert.Equal(t, []string{"a=1:expired", "b=2:expired"}, log.get())
+	assert.Equal(t, uint64(2), c.Stats().Evictions)
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCacheCost(t *testing.T) {
+	c := NewCache(CacheOptions[string, string]{
+		MaxCost: 10,
+		Shards:  1,
+		Cost:    func(_ string, v string) int64 { return int64(len(v)) },
+	})
+
+	c.Set("a", "12345")
+	c.Set("b", "1234")
+	assert.Equal(t, int64(9), c.Cost())
+
+	c.Set("c", "12")
+	assert.Equal(t, int64(6), c.Cost())
+	_, found := c.Get("a")
+	assert.False(t, found)
+
+	c.Set("huge", "12345678901")
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, int64(0), c.Cost())
+}
+
+func TestCacheLimitsSpanShards(t *testing.T) {
+	clock := clocktest.NewManual()
+	c := NewCache(CacheOptions[int, int]{MaxEntries: 20, Clock: clock})
+	for i := 0; i < 200; i++ {
+		c.Set(i, i)
+		assert.Equal(t, min(i+1, 20), c.Len())
+	}
+
+	for i := 0; i < 10; i++ {
+		c.SetWithTTL(1000+i, i, time.Second)
+		assert.Equal(t, 20, c.Len())
+	}
+
+	// Only expiry takes the cache below its limit.
+	clock.Advance(time.Second)
+	expired := c.DeleteExpired()
+	assert.Positive(t, expired)
+	assert.Equal(t, 20-expired, c.Len())
+
+	costly := NewCache(CacheOptions[int, int]{MaxCost: 10, Cost: func(_, v int) int64 { return int64(v) }})
+	for i := 0; i < 100; i++ {
+		costly.Set(i, 3)
+		assert.LessOrEqual(t, costly.Cost(), int64(10))
+	}
+
+	assert.Equal(t, 3, costly.Len())
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(CacheOptions[int, string]{})
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(_ context.Context, key int) (string, error) {
+		calls.Add(1)
+		<-release
+		return fmt.Sprint(key), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(ctx, 7, load)
+			assert.NoError(t, err)
+			assert.Equal(t, "7", v)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+
+	v, err := c.GetOrLoad(ctx, 7, load)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", v)
+	assert.Equal(t, int32(1), calls.Load())
+
+	_, err = c.GetOrLoad(ctx, 8, func(context.Context, int) (string, error) { return "", errors.New("down") })
+	assert.EqualError(t, err, "down")
+	_, found := c.Get(8)
+	assert.False(t, found)
+}
+
+func TestCacheGetOrLoadWaiterCancel(t *testing.T) {
+	c := NewCache(CacheOptions[string, int]{})
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_, _ = c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+	}()
+
+	<-started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.GetOrLoad(ctx, "k", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	close(release)
+}
+
+func TestCacheGetOrLoadPanic(t *testing.T) {
+	c := NewCache(CacheOptions[string, int]{})
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer func() {
+			p, ok := recover().(*LoadPanicError)
+			assert.True(t, ok)
+			assert.Equal(t, "boom", p.Value)
+			assert.Contains(t, string(p.Stack), "TestCacheGetOrLoadPanic")
+			assert.ErrorContains(t, p, "structs: cache load panicked: boom")
+		}()
+
+		_, _ = c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+
+	<-started
+	waiter := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(context.Background(), "k", nil)
+		waiter <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.EqualError(t, <-waiter, "panic: boom")
+
+	// The failed load is forgotten.
+	v, err := c.GetOrLoad(context.Background(), "k", func(context.Context, string) (int, error) { return 2, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestCacheNeedsHash(t *testing.T) {
+	type point struct{ x, y int }
+	assert.PanicsWithValue(t, "structs: NewCache needs CacheOptions.Hash for key type structs.point", func() {
+		NewCache(CacheOptions[point, int]{})
+	})
+
+	c := NewCache(CacheOptions[point, int]{Hash: func(p point) uint64 { return uint64(p.x) }})
+	c.Set(point{1, 2}, 3)
+	v, _ := c.Get(point{1, 2})
+	assert.Equal(t, 3, v)
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	type key struct{ a, b int }
+	c := NewCache(CacheOptions[key, int]{MaxEntries: 256, Policy: LFU, Hash: func(k key) uint64 { return mix64(uint64(k.a)<<32 | uint64(k.b)) }})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				k := key{g, i % 300}
+				if v, found := c.Get(k); found {
+					assert.Equal(t, k.b, v)
+				} else {
+					c.Set(k, k.b)
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	assert.LessOrEqual(t, c.Len(), 256)
+}
+
+func BenchmarkCacheGet(b *testing.B) {
+	c := NewCache(CacheOptions[int, int]{MaxEntries: 1 << 16})
+	for i := 0; i < 1<<16; i++ {
+		c.Set(i, i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(i & (1<<16 - 1))
+			i++
+		}
+	})
+}
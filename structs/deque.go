@@ -0,0 +1,319 @@
+package structs
+
+import "iter"
+
+// dequeChunkSize is the number of elements per Deque chunk.
+const dequeChunkSize = 64
+
+type dequeChunk[T any] [dequeChunkSize]T
+
+// Deque is a double-ended queue on a chunked ring buffer: a ring of pointers
+// to fixed-size chunks. Pushing and popping at either end is O(1), growing
+// never copies elements, and At is O(1). Removed slots are zeroed and empty
+// chunks released, keeping one spare to avoid churn at a chunk boundary.
+//
+// Push, Pop and Peek work on the back and front respectively, so a Deque is
+// a TypedQueue. A Deque is not safe for concurrent use.
+type Deque[T any] struct {
+	chunks []*dequeChunk[T]
+	first  int // index in chunks of the chunk holding the front element
+	used   int // number of chunks in use
+	off    int // offset of the front element in its chunk
+	size   int
+	spare  *dequeChunk[T]
+}
+
+// NewDeque creates an empty Deque.
+func NewDeque[T any]() *Deque[T] {
+	return &Deque[T]{}
+}
+
+// Len returns the number of elements.
+func (d *Deque[T]) Len() int {
+	return d.size
+}
+
+// PushBack adds v at the back.
+func (d *Deque[T]) PushBack(v T) {
+	if d.off+d.size == d.used*dequeChunkSize {
+		d.reserve()
+		d.chunks[(d.first+d.used)%len(d.chunks)] = d.newChunk()
+		d.used++
+	}
+
+	d.size++
+	*d.slot(d.size - 1) = v
+}
+
+// PushFront adds v at the front.
+func (d *Deque[T]) PushFront(v T) {
+	if d.off == 0 {
+		d.reserve()
+		d.first = (d.first - 1 + len(d.chunks)) % len(d.chunks)
+		d.chunks[d.first] = d.newChunk()
+		d.used++
+		d.off = dequeChunkSize
+	}
+
+	d.off--
+	d.size++
+	*d.slot(0) = v
+}
+
+// PopFront removes and returns the front element.
+func (d *Deque[T]) PopFront() (T, bool) {
+	var zero T
+	if d.size == 0 {
+		return zero, false
+	}
+
+	slot := d.slot(0)
+	v := *slot
+	*slot = zero
+	d.off++
+	d.size--
+
+	switch {
+	case d.size == 0:
+		d.empty()
+	case d.off == dequeChunkSize:
+		d.releaseChunk(d.first)
+		d.first = (d.first + 1) % len(d.chunks)
+		d.off = 0
+	}
+
+	d.compact()
+	return v, true
+}
+
+// PopBack removes and returns the back element.
+func (d *Deque[T]) PopBack() (T, bool) {
+	var zero T
+	if d.size == 0 {
+		return zero, false
+	}
+
+	slot := d.slot(d.size - 1)
+	v := *slot
+	*slot = zero
+	d.size--
+
+	switch {
+	case d.size == 0:
+		d.empty()
+	case d.off+d.size <= (d.used-1)*dequeChunkSize:
+		d.releaseChunk((d.first + d.used - 1) % len(d.chunks))
+	}
+
+	d.compact()
+	return v, true
+}
+
+// Front returns the front element without removing it.
+func (d *Deque[T]) Front() (T, bool) {
+	if d.size == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return *d.slot(0), true
+}
+
+// Back returns the back element without removing it.
+func (d *Deque[T]) Back() (T, bool) {
+	if d.size == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return *d.slot(d.size - 1), true
+}
+
+// Push is PushBack, for TypedQueue.
+func (d *Deque[T]) Push(v T) {
+	d.PushBack(v)
+}
+
+// Pop is PopFront, for TypedQueue.
+func (d *Deque[T]) Pop() (T, bool) {
+	return d.PopFront()
+}
+
+// Peek is Front, for TypedQueue.
+func (d *Deque[T]) Peek() (T, bool) {
+	return d.Front()
+}
+
+// At returns the element at index i, counting from the front. It panics if
+// i is out of range.
+func (d *Deque[T]) At(i int) T {
+	if i < 0 || i >= d.size {
+		panic("structs: Deque index out of range")
+	}
+
+	return *d.slot(i)
+}
+
+// Set replaces the element at index i. It panics if i is out of range.
+func (d *Deque[T]) Set(i int, v T) {
+	if i < 0 || i >= d.size {
+		panic("structs: Deque index out of range")
+	}
+
+	*d.slot(i) = v
+}
+
+// Rotate moves the last n elements to the front, or the first -n elements to
+// the back when n is negative. It takes O(min(|n|, Len-|n|)) steps.
+func (d *Deque[T]) Rotate(n int) {
+	if d.size <= 1 {
+		return
+	}
+
+	n %= d.size
+	if n < 0 {
+		n += d.size
+	}
+
+	if n <= d.size/2 {
+		for ; n > 0; n-- {
+			v, _ := d.PopBack()
+			d.PushFront(v)
+		}
+
+		return
+	}
+
+	for n = d.size - n; n > 0; n-- {
+		v, _ := d.PopFront()
+		d.PushBack(v)
+	}
+}
+
+// Clear removes all elements and releases the chunks.
+func (d *Deque[T]) Clear() {
+	*d = Deque[T]{}
+}
+
+// All returns the elements from front to back. The deque must not be
+// modified during iteration.
+func (d *Deque[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < d.size; i++ {
+			if !yield(*d.slot(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns the elements from back to front.
+func (d *Deque[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := d.size - 1; i >= 0; i-- {
+			if !yield(*d.slot(i)) {
+				return
+			}
+		}
+	}
+}
+
+func (d *Deque[T]) slot(i int) *T {
+	p := d.off + i
+	return &d.chunks[(d.first+p/dequeChunkSize)%len(d.chunks)][p%dequeChunkSize]
+}
+
+func (d *Deque[T]) newChunk() *dequeChunk[T] {
+	if chunk := d.spare; chunk != nil {
+		d.spare = nil
+		return chunk
+	}
+
+	return new(dequeChunk[T])
+}
+
+// releaseChunk drops the chunk at index i in chunks, which must be an end of
+// the used range and already zeroed.
+func (d *Deque[T]) releaseChunk(i int) {
+	d.spare = d.chunks[i]
+	d.chunks[i] = nil
+	d.used--
+}
+
+// empty releases the chunks of a deque whose last element was just removed.
+func (d *Deque[T]) empty() {
+	for i := 0; i < d.used; i++ {
+		d.releaseChunk((d.first + i) % len(d.chunks))
+	}
+
+	d.first, d.off = 0, 0
+}
+
+// reserve makes room in chunks for one more chunk.
+func (d *Deque[T]) reserve() {
+	if d.used < len(d.chunks) {
+		return
+	}
+
+	d.resize(max(2*len(d.chunks), 4))
+}
+
+// compact halves the chunk ring when it is mostly empty.
+func (d *Deque[T]) compact() {
+	if len(d.chunks) > 4 && d.used <= len(d.chunks)/4 {
+		d.resize(len(d.chunks) / 2)
+	}
+}
+
+func (d *Deque[T]) resize(n int) {
+	chunks := make([]*dequeChunk[T], n)
+	for i := 0; i < d.used; i++ {
+		chunks[i] = d.chunks[(d.first+i)%len(d.chunks)]
+	}
+
+	d.chunks, d.first = chunks, 0
+}
+
+// Stack is a LIFO stack on a Deque. Push and Pop work on the top; All
+// iterates from the top down. A Stack is not safe for concurrent use.
+//
+// Although its methods match TypedQueue, a Stack is not first-in first-out
+// and should not be passed where a queue is expected.
+type Stack[T any] struct {
+	d Deque[T]
+}
+
+// NewStack creates an empty Stack.
+func NewStack[T any]() *Stack[T] {
+	return &Stack[T]{}
+}
+
+// Push adds v on top.
+func (s *Stack[T]) Push(v T) {
+	s.d.PushBack(v)
+}
+
+// Pop removes and returns the top element.
+func (s *Stack[T]) Pop() (T, bool) {
+	return s.d.PopBack()
+}
+
+// Peek returns the top element without removing it.
+func (s *Stack[T]) Peek() (T, bool) {
+	return s.d.Back()
+}
+
+// Len returns the number of elements.
+func (s *Stack[T]) Len() int {
+	return s.d.Len()
+}
+
+// Clear removes all elements.
+func (s *Stack[T]) Clear() {
+	s.d.Clear()
+}
+
+// All returns the elements from the top down.
+func (s *Stack[T]) All() iter.Seq[T] {
+	return s.d.Backward()
+}
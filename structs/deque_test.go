@@ -0,0 +1,168 @@
+package structs
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeque(t *testing.T) {
+	var q TypedQueue[int] = NewDeque[int]()
+	d := q.(*Deque[int])
+
+	_, ok := d.PopFront()
+	assert.False(t, ok)
+	_, ok = d.PopBack()
+	assert.False(t, ok)
+	_, ok = d.Back()
+	assert.False(t, ok)
+
+	d.PushBack(2)
+	d.PushFront(1)
+	d.PushBack(3)
+	assert.Equal(t, []int{1, 2, 3}, slices.Collect(d.All()))
+	assert.Equal(t, []int{3, 2, 1}, slices.Collect(d.Backward()))
+	assert.Equal(t, 2, d.At(1))
+
+	d.Set(1, 20)
+	assert.Equal(t, 20, d.At(1))
+	assert.Panics(t, func() { d.At(3) })
+	assert.Panics(t, func() { d.Set(-1, 0) })
+
+	v, _ := q.Peek()
+	assert.Equal(t, 1, v)
+	v, _ = q.Pop()
+	assert.Equal(t, 1, v)
+	v, _ = d.PopBack()
+	assert.Equal(t, 3, v)
+	assert.Equal(t, 1, q.Len())
+
+	d.Clear()
+	assert.Equal(t, 0, d.Len())
+	assert.Empty(t, slices.Collect(d.All()))
+}
+
+func TestDequeRotate(t *testing.T) {
+	d := NewDeque[int]()
+	d.Rotate(3)
+	for i := 0; i < 5; i++ {
+		d.PushBack(i)
+	}
+
+	d.Rotate(2)
+	assert.Equal(t, []int{3, 4, 0, 1, 2}, slices.Collect(d.All()))
+	d.Rotate(-2)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, slices.Collect(d.All()))
+	d.Rotate(4)
+	assert.Equal(t, []int{1, 2, 3, 4, 0}, slices.Collect(d.All()))
+	d.Rotate(11)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, slices.Collect(d.All()))
+}
+
+func TestDequeMatchesSlice(t *testing.T) {
+	d := NewDeque[int]()
+	var model []int
+	for i := 0; i < 20000; i++ {
+		switch op := rand.IntN(10); {
+		case op < 3:
+			d.PushBack(i)
+			model = append(model, i)
+		case op < 6:
+			d.PushFront(i)
+			model = append([]int{i}, model...)
+		case op < 8:
+			v, ok := d.PopFront()
+			assert.Equal(t, len(model) > 0, ok)
+			if len(model) > 0 {
+				assert.Equal(t, model[0], v)
+				model = model[1:]
+			}
+		default:
+			v, ok := d.PopBack()
+			assert.Equal(t, len(model) > 0, ok)
+			if len(model) > 0 {
+				assert.Equal(t, model[len(model)-1], v)
+				model = model[:len(model)-1]
+			}
+		}
+
+		if i%997 == 0 {
+			n := rand.IntN(200) - 100
+			d.Rotate(n)
+			if len(model) > 0 {
+				k := ((n % len(model)) + len(model)) % len(model)
+				model = append(model[len(model)-k:], model[:len(model)-k]...)
+			}
+		}
+
+		assert.Equal(t, len(model), d.Len())
+	}
+
+	assert.Equal(t, model, append([]int{}, slices.Collect(d.All())...))
+	for i, v := range model {
+		assert.Equal(t, v, d.At(i))
+	}
+}
+
+func TestDequeReleasesChunks(t *testing.T) {
+	d := NewDeque[*int]()
+	for i := 0; i < 10*dequeChunkSize; i++ {
+		d.PushBack(new(int))
+	}
+
+	for i := 0; i < 10*dequeChunkSize-1; i++ {
+		d.PopFront()
+	}
+
+	assert.Equal(t, 1, d.used)
+	assert.LessOrEqual(t, len(d.chunks), 8)
+
+	// Only the last element is still referenced.
+	var live int
+	for _, chunk := range append(d.chunks, d.spare) {
+		if chunk == nil {
+			continue
+		}
+
+		for _, p := range chunk {
+			if p != nil {
+				live++
+			}
+		}
+	}
+
+	assert.Equal(t, 1, live)
+}
+
+func TestStack(t *testing.T) {
+	s := NewStack[string]()
+	_, ok := s.Pop()
+	assert.False(t, ok)
+
+	s.Push("a")
+	s.Push("b")
+	s.Push("c")
+	assert.Equal(t, 3, s.Len())
+	assert.Equal(t, []string{"c", "b", "a"}, slices.Collect(s.All()))
+
+	v, _ := s.Peek()
+	assert.Equal(t, "c", v)
+	v, _ = s.Pop()
+	assert.Equal(t, "c", v)
+	v, _ = s.Pop()
+	assert.Equal(t, "b", v)
+
+	s.Clear()
+	assert.Equal(t, 0, s.Len())
+}
+
+func BenchmarkDeque(b *testing.B) {
+	d := NewDeque[int]()
+	for i := 0; i < b.N; i++ {
+		d.PushBack(i)
+		d.PushFront(i)
+		d.PopBack()
+	}
+}
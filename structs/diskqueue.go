@@ -0,0 +1,566 @@
+package structs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrQueueEmpty is returned by DiskQueue.Pop when every record has been delivered.
+	ErrQueueEmpty = errors.New("structs: queue empty")
+
+	// ErrNotDelivered is returned when acknowledging a record that has not been popped.
+	ErrNotDelivered = errors.New("structs: record not delivered")
+
+	// ErrRecordTooLarge is returned when pushing a record larger than MaxDiskRecordSize.
+	ErrRecordTooLarge = errors.New("structs: record too large")
+
+	// ErrCorruptRecord is returned by DiskQueue.Pop for records that cannot be
+	// read back intact. They are skipped, so the next Pop moves on.
+	ErrCorruptRecord = errors.New("structs: corrupt record")
+
+	errChecksumMismatch = fmt.Errorf("%w: checksum mismatch", ErrCorruptRecord)
+)
+
+const (
+	// MaxDiskRecordSize bounds the payload of a DiskQueue record.
+	MaxDiskRecordSize = 64 << 20
+
+	// DefaultSegmentSize is the segment size used when DiskQueueOptions.SegmentSize is zero.
+	DefaultSegmentSize = 16 << 20
+
+	diskRecordHeader = 8
+	segmentSuffix    = ".seg"
+	offsetFileName   = "consumer.offset"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// SyncPolicy decides when a DiskQueue flushes writes to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncNever leaves flushing to the operating system, except on Close.
+	SyncNever SyncPolicy = iota
+	// SyncAlways flushes after every Push and Ack.
+	SyncAlways
+	// SyncBatch flushes after every DiskQueueOptions.SyncBatchSize pushes.
+	SyncBatch
+)
+
+// DiskQueueOptions configures a DiskQueue.
+type DiskQueueOptions struct {
+	// SegmentSize is the size at which a new segment file is started.
+	SegmentSize int64
+
+	// Sync is the flush policy.
+	Sync SyncPolicy
+
+	// SyncBatchSize is the number of pushes between flushes for SyncBatch.
+	SyncBatchSize int
+}
+
+// DiskRecord is a record popped from a DiskQueue.
+type DiskRecord struct {
+	Offset uint64
+	Data   []byte
+}
+
+type diskSegment struct {
+	start uint64
+	path  string
+}
+
+// DiskQueue is a persistent FIFO queue of byte records in a directory.
+//
+// Records are appended to segment files named after the offset of their first
+// record. Each record is stored as its length, a CRC-32C checksum and the
+// payload. Pop delivers records in order; Ack(offset) marks every record up to
+// offset as consumed, persists that position and deletes segments that hold
+// only consumed records. Records popped but not acknowledged are delivered
+// again after Rewind or a restart, so consumers see each record at least once.
+//
+// Opening a queue recovers from a crash by truncating the last segment at the
+// first incomplete or corrupt record, which is what an interrupted write
+// leaves behind. Earlier segments are not checked on open; Pop skips damaged
+// records in them and reports ErrCorruptRecord, losing the rest of a segment
+// whose record boundaries can no longer be found. A DiskQueue is safe for
+// concurrent use.
+type DiskQueue struct {
+	mu   sync.Mutex
+	dir  string
+	opts DiskQueueOptions
+
+	segments []diskSegment
+	writer   *os.File
+	written  int64  // size of the active segment
+	next     uint64 // offset of the next pushed record
+	unsynced int
+
+	reader     *os.File
+	readOffset uint64 // offset of the next popped record
+	readSeg    int    // index in segments of the reader's segment
+	acked      uint64 // offset of the first unacknowledged record
+}
+
+// OpenDiskQueue opens or creates the queue stored in dir.
+func OpenDiskQueue(dir string, opts DiskQueueOptions) (*DiskQueue, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	q := &DiskQueue{dir: dir, opts: opts}
+	if err := q.recover(); err != nil {
+		q.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (q *DiskQueue) recover() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+
+		start, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		q.segments = append(q.segments, diskSegment{start: start, path: filepath.Join(q.dir, name)})
+	}
+
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].start < q.segments[j].start })
+
+	if q.acked, err = q.readAckedOffset(); err != nil {
+		return err
+	}
+
+	if len(q.segments) == 0 {
+		q.next = q.acked
+		if err := q.startSegment(); err != nil {
+			return err
+		}
+	} else {
+		last := q.segments[len(q.segments)-1]
+		count, size, err := scanSegment(last.path)
+		if err != nil {
+			return err
+		}
+
+		if q.writer, err = os.OpenFile(last.path, os.O_RDWR, 0o644); err != nil {
+			return err
+		}
+
+		// Drop a torn or corrupt tail left by a crash.
+		if err := q.writer.Truncate(size); err != nil {
+			return err
+		}
+
+		if _, err := q.writer.Seek(size, io.SeekStart); err != nil {
+			return err
+		}
+
+		q.written, q.next = size, last.start+count
+	}
+
+	q.acked = min(max(q.acked, q.segments[0].start), q.next)
+	q.readOffset = q.acked
+	return q.openReader()
+}
+
+// scanSegment counts the valid records at the start of a segment file and
+// returns the size they occupy.
+func scanSegment(path string) (count uint64, size int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	for {
+		n, err := skipRecord(f, true)
+		if err != nil {
+			return count, size, nil
+		}
+
+		count++
+		size += n
+	}
+}
+
+// readRecord reads one record, verifying its checksum.
+func readRecord(r io.Reader) ([]byte, error) {
+	var header [diskRecordHeader]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > MaxDiskRecordSize {
+		return nil, fmt.Errorf("%w: length %d", ErrCorruptRecord, length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	if crc32.Checksum(data, castagnoli) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errChecksumMismatch
+	}
+
+	return data, nil
+}
+
+// skipRecord reads past one record and returns its size on disk.
+func skipRecord(r io.Reader, verify bool) (int64, error) {
+	if verify {
+		data, err := readRecord(r)
+		return int64(diskRecordHeader + len(data)), err
+	}
+
+	var header [diskRecordHeader]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+
+	length := int64(binary.BigEndian.Uint32(header[0:4]))
+	if _, err := io.CopyN(io.Discard, r, length); err != nil {
+		return 0, err
+	}
+
+	return diskRecordHeader + length, nil
+}
+
+func (q *DiskQueue) readAckedOffset() (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(q.dir, offsetFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func (q *DiskQueue) startSegment() error {
+	path := filepath.Join(q.dir, fmt.Sprintf("%020d%s", q.next, segmentSuffix))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if q.writer != nil {
+		if err := q.writer.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+
+		q.writer.Close()
+	}
+
+	q.writer, q.written = f, 0
+	q.segments = append(q.segments, diskSegment{start: q.next, path: path})
+	return syncDir(q.dir, q.opts.Sync)
+}
+
+// openReader positions the reader at readOffset.
+func (q *DiskQueue) openReader() error {
+	if q.reader != nil {
+		q.reader.Close()
+		q.reader = nil
+	}
+
+	q.readSeg = sort.Search(len(q.segments), func(i int) bool { return q.segments[i].start > q.readOffset }) - 1
+	segment := q.segments[q.readSeg]
+	f, err := os.Open(segment.path)
+	if err != nil {
+		return err
+	}
+
+	for i := segment.start; i < q.readOffset; i++ {
+		if _, err := skipRecord(f, false); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	q.reader = f
+	return nil
+}
+
+// Push appends data and returns its offset.
+func (q *DiskQueue) Push(data []byte) (uint64, error) {
+	if len(data) > MaxDiskRecordSize {
+		return 0, ErrRecordTooLarge
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.writer == nil {
+		return 0, ErrQueueClosed
+	}
+
+	if q.written > 0 && q.written+int64(diskRecordHeader+len(data)) > q.opts.SegmentSize {
+		if err := q.startSegment(); err != nil {
+			return 0, err
+		}
+	}
+
+	record := make([]byte, diskRecordHeader+len(data))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(data, castagnoli))
+	copy(record[diskRecordHeader:], data)
+	if _, err := q.writer.Write(record); err != nil {
+		// Cut off whatever part of the record made it to the file.
+		_ = q.writer.Truncate(q.written)
+		_, _ = q.writer.Seek(q.written, io.SeekStart)
+		return 0, err
+	}
+
+	q.written += int64(len(record))
+	offset := q.next
+	q.next++
+
+	q.unsynced++
+	if q.opts.Sync == SyncAlways || (q.opts.Sync == SyncBatch && q.unsynced >= max(q.opts.SyncBatchSize, 1)) {
+		if err := q.syncLocked(); err != nil {
+			return offset, err
+		}
+	}
+
+	return offset, nil
+}
+
+// Pop returns the next undelivered record, or ErrQueueEmpty. A record that
+// fails its checksum is skipped with an ErrCorruptRecord error, and so is the
+// rest of its segment when the record is truncated or its length is damaged.
+func (q *DiskQueue) Pop() (DiskRecord, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.reader == nil {
+		return DiskRecord{}, ErrQueueClosed
+	}
+
+	if q.readOffset >= q.next {
+		return DiskRecord{}, ErrQueueEmpty
+	}
+
+	if q.readSeg+1 < len(q.segments) && q.readOffset >= q.segments[q.readSeg+1].start {
+		if err := q.openReader(); err != nil {
+			return DiskRecord{}, err
+		}
+	}
+
+	data, err := readRecord(q.reader)
+	if err != nil {
+		return DiskRecord{}, q.skipCorrupt(err)
+	}
+
+	record := DiskRecord{Offset: q.readOffset, Data: data}
+	q.readOffset++
+	return record, nil
+}
+
+// skipCorrupt moves the reader past a record that readRecord failed on and
+// returns the error for Pop. Other errors leave the reader where it was, so a
+// later Pop retries the record. It must be called with mu held.
+func (q *DiskQueue) skipCorrupt(err error) error {
+	offset := q.readOffset
+	if errors.Is(err, errChecksumMismatch) {
+		// The reader is already past the record.
+		q.readOffset++
+		return fmt.Errorf("record %d: %w", offset, err)
+	}
+
+	if !errors.Is(err, ErrCorruptRecord) && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if reopenErr := q.openReader(); reopenErr != nil {
+			return errors.Join(err, reopenErr)
+		}
+
+		return err
+	}
+
+	// The record boundaries are lost: skip to the next segment, starting one
+	// if the damage is in the active segment.
+	if q.readSeg+1 == len(q.segments) {
+		if startErr := q.startSegment(); startErr != nil {
+			return errors.Join(err, startErr)
+		}
+	}
+
+	q.readOffset = q.segments[q.readSeg+1].start
+	corrupt := fmt.Errorf("records %d to %d: %w", offset, q.readOffset-1, err)
+	if !errors.Is(err, ErrCorruptRecord) {
+		corrupt = fmt.Errorf("records %d to %d: %w: %w", offset, q.readOffset-1, ErrCorruptRecord, err)
+	}
+
+	if reopenErr := q.openReader(); reopenErr != nil {
+		return errors.Join(corrupt, reopenErr)
+	}
+
+	return corrupt
+}
+
+// Ack marks every record up to and including offset as consumed, persists
+// the position and deletes fully consumed segments.
+func (q *DiskQueue) Ack(offset uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.writer == nil {
+		return ErrQueueClosed
+	}
+
+	if offset >= q.readOffset {
+		return fmt.Errorf("%w: %d", ErrNotDelivered, offset)
+	}
+
+	if offset < q.acked {
+		return nil
+	}
+
+	q.acked = offset + 1
+	if err := q.writeAckedOffset(); err != nil {
+		return err
+	}
+
+	for len(q.segments) > 1 && q.segments[1].start <= q.acked {
+		if err := os.Remove(q.segments[0].path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		q.segments = q.segments[1:]
+		q.readSeg--
+	}
+
+	return nil
+}
+
+func (q *DiskQueue) writeAckedOffset() error {
+	path := filepath.Join(q.dir, offsetFileName)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(strconv.FormatUint(q.acked, 10))
+	if err == nil && q.opts.Sync != SyncNever {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Rewind makes the records popped but not yet acknowledged available again.
+func (q *DiskQueue) Rewind() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.reader == nil {
+		return ErrQueueClosed
+	}
+
+	q.readOffset = q.acked
+	return q.openReader()
+}
+
+// Len returns the number of records not yet popped.
+func (q *DiskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int(q.next - q.readOffset)
+}
+
+// Pending returns the number of records not yet acknowledged.
+func (q *DiskQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int(q.next - q.acked)
+}
+
+// Sync flushes pushed records to stable storage.
+func (q *DiskQueue) Sync() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.writer == nil {
+		return ErrQueueClosed
+	}
+
+	return q.syncLocked()
+}
+
+func (q *DiskQueue) syncLocked() error {
+	q.unsynced = 0
+	return q.writer.Sync()
+}
+
+// Close flushes and closes the queue.
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var err error
+	if q.writer != nil {
+		err = errors.Join(q.writer.Sync(), q.writer.Close())
+		q.writer = nil
+	}
+
+	if q.reader != nil {
+		q.reader.Close()
+		q.reader = nil
+	}
+
+	return err
+}
+
+// syncDir flushes directory entries so that new segments survive a crash.
+func syncDir(dir string, policy SyncPolicy) error {
+	if policy == SyncNever {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,258 @@
+package structs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pushAll(t *testing.T, q *DiskQueue, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		_, err := q.Push([]byte(fmt.Sprintf("record-%d", i)))
+		assert.NoError(t, err)
+	}
+}
+
+func popString(t *testing.T, q *DiskQueue) (uint64, string) {
+	t.Helper()
+	record, err := q.Pop()
+	assert.NoError(t, err)
+	return record.Offset, string(record.Data)
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	assert.NoError(t, err)
+	return files
+}
+
+func TestDiskQueue(t *testing.T) {
+	q, err := OpenDiskQueue(t.TempDir(), DiskQueueOptions{Sync: SyncAlways})
+	assert.NoError(t, err)
+	defer q.Close()
+
+	_, err = q.Pop()
+	assert.Equal(t, ErrQueueEmpty, err)
+
+	offset, err := q.Push([]byte("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), offset)
+	offset, _ = q.Push([]byte(""))
+	assert.Equal(t, uint64(1), offset)
+	assert.Equal(t, 2, q.Len())
+
+	offset, data := popString(t, q)
+	assert.Equal(t, uint64(0), offset)
+	assert.Equal(t, "a", data)
+	_, data = popString(t, q)
+	assert.Equal(t, "", data)
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, 2, q.Pending())
+
+	assert.ErrorIs(t, q.Ack(2), ErrNotDelivered)
+	assert.NoError(t, q.Ack(0))
+	assert.Equal(t, 1, q.Pending())
+
+	assert.NoError(t, q.Rewind())
+	offset, _ = popString(t, q)
+	assert.Equal(t, uint64(1), offset)
+
+	_, err = q.Push(make([]byte, MaxDiskRecordSize+1))
+	assert.Equal(t, ErrRecordTooLarge, err)
+
+	assert.NoError(t, q.Close())
+	_, err = q.Push([]byte("x"))
+	assert.Equal(t, ErrQueueClosed, err)
+	_, err = q.Pop()
+	assert.Equal(t, ErrQueueClosed, err)
+}
+
+func TestDiskQueueRestart(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDiskQueue(dir, DiskQueueOptions{})
+	assert.NoError(t, err)
+	pushAll(t, q, 0, 5)
+
+	popString(t, q)
+	popString(t, q)
+	popString(t, q)
+	assert.NoError(t, q.Ack(1))
+	assert.NoError(t, q.Close())
+
+	// Record 2 was popped but not acknowledged, so it is delivered again.
+	q, err = OpenDiskQueue(dir, DiskQueueOptions{})
+	assert.NoError(t, err)
+	defer q.Close()
+
+	assert.Equal(t, 3, q.Len())
+	offset, data := popString(t, q)
+	assert.Equal(t, uint64(2), offset)
+	assert.Equal(t, "record-2", data)
+
+	offset, _ = q.Push([]byte("more"))
+	assert.Equal(t, uint64(5), offset)
+}
+
+func TestDiskQueueSegments(t *testing.T) {
+	dir := t.TempDir()
+	// Every segment holds four records of 8 bytes header plus 8 bytes payload.
+	q, err := OpenDiskQueue(dir, DiskQueueOptions{SegmentSize: 64, Sync: SyncBatch, SyncBatchSize: 3})
+	assert.NoError(t, err)
+	pushAll(t, q, 0, 10)
+	assert.Len(t, segmentFiles(t, dir), 3)
+
+	for i := 0; i < 10; i++ {
+		offset, data := popString(t, q)
+		assert.Equal(t, uint64(i), offset)
+		assert.Equal(t, fmt.Sprintf("record-%d", i), data)
+	}
+
+	assert.NoError(t, q.Ack(3))
+	assert.Len(t, segmentFiles(t, dir), 2)
+	assert.NoError(t, q.Ack(9))
+	assert.Len(t, segmentFiles(t, dir), 1)
+
+	assert.NoError(t, q.Rewind())
+	_, err = q.Pop()
+	assert.Equal(t, ErrQueueEmpty, err)
+	assert.NoError(t, q.Close())
+
+	q, err = OpenDiskQueue(dir, DiskQueueOptions{SegmentSize: 64})
+	assert.NoError(t, err)
+	defer q.Close()
+
+	assert.Equal(t, 0, q.Len())
+	offset, _ := q.Push([]byte("record-x"))
+	assert.Equal(t, uint64(10), offset)
+	_, data := popString(t, q)
+	assert.Equal(t, "record-x", data)
+}
+
+func TestDiskQueueTornWrite(t *testing.T) {
+	for name, corrupt := range map[string]func(data []byte) []byte{
+		"partial header":  func(data []byte) []byte { return append(data, 0, 0, 0) },
+		"partial payload": func(data []byte) []byte { return append(data, 0, 0, 0, 8, 1, 2, 3, 4, 'x') },
+		"bad checksum": func(data []byte) []byte {
+			data[len(data)-1] ^= 0xff
+			return data
+		},
+	} {
+		dir := t.TempDir()
+		q, err := OpenDiskQueue(dir, DiskQueueOptions{})
+		assert.NoError(t, err)
+		pushAll(t, q, 0, 3)
+		assert.NoError(t, q.Close())
+
+		path := segmentFiles(t, dir)[0]
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(path, corrupt(data), 0o644))
+
+		q, err = OpenDiskQueue(dir, DiskQueueOptions{})
+		assert.NoError(t, err, name)
+
+		want := 3
+		if name == "bad checksum" {
+			want = 2
+		}
+
+		assert.Equal(t, want, q.Len(), name)
+		offset, err := q.Push([]byte("after"))
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(want), offset, name)
+
+		var last string
+		for i := 0; i <= want; i++ {
+			_, last = popString(t, q)
+		}
+
+		assert.Equal(t, "after", last, name)
+		assert.NoError(t, q.Close())
+	}
+}
+
+func TestDiskQueueCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	// Every segment holds four records of 8 bytes header plus 8 bytes payload.
+	q, err := OpenDiskQueue(dir, DiskQueueOptions{SegmentSize: 64})
+	assert.NoError(t, err)
+	pushAll(t, q, 0, 10)
+	assert.NoError(t, q.Close())
+
+	files := segmentFiles(t, dir)
+	assert.Len(t, files, 3)
+
+	// Flip a payload byte of record 1 and cut record 6 short: the open only
+	// checks the last segment, so both are found by Pop.
+	data, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	data[16+diskRecordHeader] ^= 0xff
+	assert.NoError(t, os.WriteFile(files[0], data, 0o644))
+	data, err = os.ReadFile(files[1])
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(files[1], data[:2*16+3], 0o644))
+
+	q, err = OpenDiskQueue(dir, DiskQueueOptions{SegmentSize: 64})
+	assert.NoError(t, err)
+	defer q.Close()
+
+	var got []string
+	var errs []error
+	for {
+		record, err := q.Pop()
+		if err == ErrQueueEmpty {
+			break
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		got = append(got, string(record.Data))
+	}
+
+	assert.Equal(t, []string{"record-0", "record-2", "record-3", "record-4", "record-5", "record-8", "record-9"}, got)
+	assert.Len(t, errs, 2)
+	assert.ErrorIs(t, errs[0], ErrCorruptRecord)
+	assert.EqualError(t, errs[0], "record 1: structs: corrupt record: checksum mismatch")
+	assert.ErrorIs(t, errs[1], ErrCorruptRecord)
+	assert.ErrorContains(t, errs[1], "records 6 to 7")
+
+}
+
+func TestDiskQueueCorruptActiveSegment(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDiskQueue(dir, DiskQueueOptions{})
+	assert.NoError(t, err)
+	defer q.Close()
+	pushAll(t, q, 0, 3)
+
+	// Damage the length of record 1 behind the open queue's back.
+	f, err := os.OpenFile(segmentFiles(t, dir)[0], os.O_RDWR, 0)
+	assert.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 16)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	_, data := popString(t, q)
+	assert.Equal(t, "record-0", data)
+	_, err = q.Pop()
+	assert.ErrorIs(t, err, ErrCorruptRecord)
+	assert.ErrorContains(t, err, "records 1 to 2")
+	_, err = q.Pop()
+	assert.Equal(t, ErrQueueEmpty, err)
+
+	// Later pushes go to a new segment and are read normally.
+	offset, err := q.Push([]byte("after"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), offset)
+	assert.Len(t, segmentFiles(t, dir), 2)
+	_, data = popString(t, q)
+	assert.Equal(t, "after", data)
+}
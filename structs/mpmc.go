@@ -0,0 +1,122 @@
+package structs
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// cacheLinePad separates fields written by different goroutines so that they
+// do not share a cache line.
+type cacheLinePad [64]byte
+
+type mpmcSlot[T any] struct {
+	// seq is the position the slot is ready for: equal to the position when
+	// it is free for a producer, and position+1 when it holds a value.
+	seq   atomic.Uint64
+	value T
+}
+
+// MPMCQueue is a bounded lock-free FIFO queue for any number of producers
+// and consumers. It is a ring of slots, each carrying a sequence number that
+// tells producers and consumers whether the slot is theirs to use, so a push
+// or pop costs one compare-and-swap in the uncontended case.
+//
+// MPMCQueue satisfies the Queue contract through LegacyQueue.
+type MPMCQueue[T any] struct {
+	_    cacheLinePad
+	head atomic.Uint64
+	_    cacheLinePad
+	tail atomic.Uint64
+	_    cacheLinePad
+	mask uint64
+	buf  []mpmcSlot[T]
+}
+
+// NewMPMCQueue creates a queue holding at least capacity elements; the
+// capacity is rounded up to a power of two.
+func NewMPMCQueue[T any](capacity int) *MPMCQueue[T] {
+	size := uint64(2)
+	for size < uint64(capacity) {
+		size <<= 1
+	}
+
+	q := &MPMCQueue[T]{mask: size - 1, buf: make([]mpmcSlot[T], size)}
+	for i := range q.buf {
+		q.buf[i].seq.Store(uint64(i))
+	}
+
+	return q
+}
+
+// TryPush adds v to the back of the queue, reporting false when it is full.
+func (q *MPMCQueue[T]) TryPush(v T) bool {
+	pos := q.tail.Load()
+	for {
+		slot := &q.buf[pos&q.mask]
+		switch diff := int64(slot.seq.Load() - pos); {
+		case diff == 0:
+			if q.tail.CompareAndSwap(pos, pos+1) {
+				slot.value = v
+				slot.seq.Store(pos + 1)
+				return true
+			}
+
+			pos = q.tail.Load()
+		case diff < 0:
+			return false
+		default:
+			pos = q.tail.Load()
+		}
+	}
+}
+
+// Push adds v to the back of the queue, yielding the processor while it is
+// full. Use TryPush or BlockingQueue when waiting is not acceptable.
+func (q *MPMCQueue[T]) Push(v T) {
+	for !q.TryPush(v) {
+		runtime.Gosched()
+	}
+}
+
+// Pop removes and returns the element at the front of the queue, reporting
+// false when it is empty.
+func (q *MPMCQueue[T]) Pop() (T, bool) {
+	pos := q.head.Load()
+	for {
+		slot := &q.buf[pos&q.mask]
+		switch diff := int64(slot.seq.Load() - (pos + 1)); {
+		case diff == 0:
+			if q.head.CompareAndSwap(pos, pos+1) {
+				var zero T
+				v := slot.value
+				slot.value = zero
+				slot.seq.Store(pos + q.mask + 1)
+				return v, true
+			}
+
+			pos = q.head.Load()
+		case diff < 0:
+			var zero T
+			return zero, false
+		default:
+			pos = q.head.Load()
+		}
+	}
+}
+
+// Len returns the number of queued elements. Under concurrent use it is a
+// snapshot that may already be stale.
+func (q *MPMCQueue[T]) Len() int {
+	for {
+		tail := q.tail.Load()
+		head := q.head.Load()
+		if q.tail.Load() == tail {
+			return int(min(tail-head, q.mask+1))
+		}
+	}
+}
+
+// Cap returns the capacity of the queue.
+func (q *MPMCQueue[T]) Cap() int {
+	return len(q.buf)
+}
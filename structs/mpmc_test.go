@@ -0,0 +1,147 @@
+package structs
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMPMCQueue(t *testing.T) {
+	q := NewMPMCQueue[int](3)
+	assert.Equal(t, 4, q.Cap())
+
+	_, ok := q.Pop()
+	assert.False(t, ok)
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, q.TryPush(i))
+	}
+
+	assert.False(t, q.TryPush(4))
+	assert.Equal(t, 4, q.Len())
+
+	for round := 0; round < 10; round++ {
+		v, ok := q.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, round, v)
+		q.Push(round + 4)
+	}
+
+	assert.Equal(t, 4, q.Len())
+}
+
+func TestMPMCQueueReleasesPopped(t *testing.T) {
+	q := NewMPMCQueue[*int](2)
+	q.Push(new(int))
+	q.Pop()
+	assert.Nil(t, q.buf[0].value)
+}
+
+func TestMPMCQueueLegacy(t *testing.T) {
+	var queue Queue = LegacyQueue[string](NewMPMCQueue[string](4))
+	queue.Push("a")
+	queue.Push("b")
+	assert.Equal(t, "a", queue.Pop())
+	assert.Equal(t, "b", queue.Pop())
+	assert.Nil(t, queue.Pop())
+}
+
+func TestMPMCQueueConcurrent(t *testing.T) {
+	const producers, consumers, perProducer = 8, 8, 1000
+	q := NewMPMCQueue[int](64)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				q.Push(p*perProducer + i)
+			}
+		}(p)
+	}
+
+	results := make(chan []int, consumers)
+	var remaining sync.WaitGroup
+	remaining.Add(producers * perProducer)
+	done := make(chan struct{})
+	for c := 0; c < consumers; c++ {
+		go func() {
+			var got []int
+			for {
+				if v, ok := q.Pop(); ok {
+					got = append(got, v)
+					remaining.Done()
+					continue
+				}
+
+				select {
+				case <-done:
+					results <- got
+					return
+				default:
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	remaining.Wait()
+	close(done)
+
+	var all []int
+	for c := 0; c < consumers; c++ {
+		all = append(all, <-results...)
+	}
+
+	sort.Ints(all)
+	assert.Len(t, all, producers*perProducer)
+	for i, v := range all {
+		assert.Equal(t, i, v)
+	}
+}
+
+// benchmarkQueue runs push/pop pairs split across goroutines.
+func benchmarkQueue(b *testing.B, push func(int), pop func()) {
+	for _, goroutines := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("goroutines=%d", goroutines), func(b *testing.B) {
+			var wg sync.WaitGroup
+			per := b.N/goroutines + 1
+			b.ResetTimer()
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < per; i++ {
+						push(i)
+						pop()
+					}
+				}()
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
+func BenchmarkMPMCQueue(b *testing.B) {
+	q := NewMPMCQueue[int](1024)
+	benchmarkQueue(b, q.Push, func() { q.Pop() })
+}
+
+func BenchmarkChannelQueue(b *testing.B) {
+	ch := make(chan int, 1024)
+	benchmarkQueue(b, func(v int) { ch <- v }, func() { <-ch })
+}
+
+func BenchmarkBlockingQueue(b *testing.B) {
+	ctx := context.Background()
+	q := NewBlockingQueue[int](1024, OverflowBlock)
+	benchmarkQueue(b, func(v int) { _ = q.Push(ctx, v) }, func() { _, _ = q.Pop(ctx) })
+}
@@ -0,0 +1,349 @@
+package structs
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"reflect"
+	"strconv"
+)
+
+type orderedEntry[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *orderedEntry[K, V]
+}
+
+// OrderedMap is a map that remembers insertion order. Get, Set, Delete and
+// the Move methods are O(1). It encodes to a JSON object with its keys in
+// order and decodes keeping the order of the document. The zero value is an
+// empty map ready to use. Like a built-in map, a copy of an OrderedMap that
+// has been written to refers to the same entries. OrderedMap is not safe for
+// concurrent use.
+type OrderedMap[K comparable, V any] struct {
+	entries map[K]*orderedEntry[K, V]
+	root    *orderedEntry[K, V] // sentinel; root.next is the first entry
+}
+
+// NewOrderedMap creates an empty OrderedMap.
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	m := &OrderedMap[K, V]{}
+	m.init()
+	return m
+}
+
+func (m *OrderedMap[K, V]) init() {
+	if m.entries == nil {
+		m.entries = map[K]*orderedEntry[K, V]{}
+		m.root = &orderedEntry[K, V]{}
+		m.root.prev, m.root.next = m.root, m.root
+	}
+}
+
+// Get returns the value stored for key.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if e, found := m.entries[key]; found {
+		return e.value, true
+	}
+
+	var zero V
+	return zero, false
+}
+
+// Has reports whether key is present.
+func (m *OrderedMap[K, V]) Has(key K) bool {
+	_, found := m.entries[key]
+	return found
+}
+
+// Set stores value for key. A new key goes to the back; an existing key
+// keeps its position.
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	m.init()
+	if e, found := m.entries[key]; found {
+		e.value = value
+		return
+	}
+
+	e := &orderedEntry[K, V]{key: key, value: value}
+	m.entries[key] = e
+	m.link(e, m.root.prev)
+}
+
+// Delete removes key and reports whether it was present.
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	e, found := m.entries[key]
+	if !found {
+		return false
+	}
+
+	delete(m.entries, key)
+	m.unlink(e)
+	return true
+}
+
+// MoveToFront makes key the first entry and reports whether it was present.
+func (m *OrderedMap[K, V]) MoveToFront(key K) bool {
+	e, found := m.entries[key]
+	if found {
+		m.unlink(e)
+		m.link(e, m.root)
+	}
+
+	return found
+}
+
+// MoveToBack makes key the last entry and reports whether it was present.
+func (m *OrderedMap[K, V]) MoveToBack(key K) bool {
+	e, found := m.entries[key]
+	if found {
+		m.unlink(e)
+		m.link(e, m.root.prev)
+	}
+
+	return found
+}
+
+// Front returns the first entry.
+func (m *OrderedMap[K, V]) Front() (K, V, bool) {
+	if len(m.entries) == 0 {
+		return m.none()
+	}
+
+	return m.root.next.key, m.root.next.value, true
+}
+
+// Back returns the last entry.
+func (m *OrderedMap[K, V]) Back() (K, V, bool) {
+	if len(m.entries) == 0 {
+		return m.none()
+	}
+
+	return m.root.prev.key, m.root.prev.value, true
+}
+
+func (m *OrderedMap[K, V]) none() (K, V, bool) {
+	var key K
+	var value V
+	return key, value, false
+}
+
+// Len returns the number of entries.
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.entries)
+}
+
+// Clear removes every entry. Copies made before Clear keep the old entries.
+func (m *OrderedMap[K, V]) Clear() {
+	m.entries = nil
+	m.init()
+}
+
+// All returns the entries from first to last. The current entry may be
+// deleted during iteration.
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.entries == nil {
+			return
+		}
+
+		for e := m.root.next; e != m.root; {
+			next := e.next
+			if !yield(e.key, e.value) {
+				return
+			}
+
+			e = next
+		}
+	}
+}
+
+// Backward returns the entries from last to first. The current entry may be
+// deleted during iteration.
+func (m *OrderedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.entries == nil {
+			return
+		}
+
+		for e := m.root.prev; e != m.root; {
+			prev := e.prev
+			if !yield(e.key, e.value) {
+				return
+			}
+
+			e = prev
+		}
+	}
+}
+
+// Keys returns the keys in order.
+func (m *OrderedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, len(m.entries))
+	for k := range m.All() {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// Values returns the values in order.
+func (m *OrderedMap[K, V]) Values() []V {
+	values := make([]V, 0, len(m.entries))
+	for _, v := range m.All() {
+		values = append(values, v)
+	}
+
+	return values
+}
+
+// link inserts e after at.
+func (m *OrderedMap[K, V]) link(e, at *orderedEntry[K, V]) {
+	e.prev, e.next = at, at.next
+	at.next.prev = e
+	at.next = e
+}
+
+// unlink removes e from the list. Its own links are kept so that an iterator
+// positioned on e can still advance.
+func (m *OrderedMap[K, V]) unlink(e *orderedEntry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order. Keys
+// follow the encoding/json rules for map keys: strings, integers and
+// encoding.TextMarshaler implementations. The value receiver lets maps held
+// by value encode as well.
+func (m OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for k, v := range m.All() {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+
+		name, err := orderedKeyText(k)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON replaces the contents of the map with a JSON object, keeping
+// the order of its keys. A repeated key keeps its first position and its
+// last value. null yields an empty map.
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	m.Clear()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok == nil {
+		return nil
+	}
+
+	if tok != json.Delim('{') {
+		return fmt.Errorf("structs: cannot unmarshal %v into OrderedMap", tok)
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key, err := orderedKeyParse[K](tok.(string))
+		if err != nil {
+			return err
+		}
+
+		var value V
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+
+		m.Set(key, value)
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+func orderedKeyText[K comparable](key K) (string, error) {
+	v := reflect.ValueOf(&key).Elem()
+	if v.Kind() == reflect.String {
+		return v.String(), nil
+	}
+
+	if tm, ok := any(key).(encoding.TextMarshaler); ok {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return "", nil
+		}
+
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("structs: unsupported OrderedMap key type %s", v.Type())
+	}
+}
+
+func orderedKeyParse[K comparable](text string) (K, error) {
+	var key K
+	if tu, ok := any(&key).(encoding.TextUnmarshaler); ok {
+		err := tu.UnmarshalText([]byte(text))
+		return key, err
+	}
+
+	v := reflect.ValueOf(&key).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("structs: OrderedMap key %q: %w", text, err)
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("structs: OrderedMap key %q: %w", text, err)
+		}
+
+		v.SetUint(n)
+	default:
+		return key, fmt.Errorf("structs: unsupported OrderedMap key type %s", v.Type())
+	}
+
+	return key, nil
+}
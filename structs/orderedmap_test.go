@@ -0,0 +1,174 @@
+package structs
+
+import (
+	"encoding/json"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap(t *testing.T) {
+	var m OrderedMap[string, int]
+	_, _, found := m.Front()
+	assert.False(t, found)
+	assert.Empty(t, m.Keys())
+
+	m.Set("c", 1)
+	m.Set("a", 2)
+	m.Set("b", 3)
+	m.Set("c", 4)
+
+	v, found := m.Get("c")
+	assert.True(t, found)
+	assert.Equal(t, 4, v)
+	assert.True(t, m.Has("a"))
+	assert.False(t, m.Has("z"))
+	assert.Equal(t, []string{"c", "a", "b"}, m.Keys())
+	assert.Equal(t, []int{4, 2, 3}, m.Values())
+
+	assert.True(t, m.MoveToBack("c"))
+	assert.True(t, m.MoveToFront("b"))
+	assert.False(t, m.MoveToFront("z"))
+	assert.Equal(t, []string{"b", "a", "c"}, m.Keys())
+
+	k, v, found := m.Front()
+	assert.Equal(t, "b", k)
+	assert.Equal(t, 3, v)
+	assert.True(t, found)
+	k, _, _ = m.Back()
+	assert.Equal(t, "c", k)
+
+	assert.True(t, m.Delete("a"))
+	assert.False(t, m.Delete("a"))
+	assert.Equal(t, 2, m.Len())
+
+	var backward []string
+	for k := range m.Backward() {
+		backward = append(backward, k)
+	}
+
+	assert.Equal(t, []string{"c", "b"}, backward)
+
+	m.Clear()
+	assert.Equal(t, 0, m.Len())
+	m.Set("x", 1)
+	assert.Equal(t, []string{"x"}, m.Keys())
+}
+
+func TestOrderedMapDeleteWhileIterating(t *testing.T) {
+	m := NewOrderedMap[int, int]()
+	for i := 0; i < 6; i++ {
+		m.Set(i, i)
+	}
+
+	for k := range m.All() {
+		if k%2 == 0 {
+			m.Delete(k)
+		}
+	}
+
+	assert.Equal(t, []int{1, 3, 5}, m.Keys())
+
+	for k := range m.Backward() {
+		m.Delete(k)
+		break
+	}
+
+	assert.Equal(t, []int{1, 3}, m.Keys())
+}
+
+func TestOrderedMapJSON(t *testing.T) {
+	m := NewOrderedMap[string, any]()
+	m.Set("zeta", 1)
+	m.Set("alpha", []int{1, 2})
+	m.Set("<tag>", nil)
+
+	data, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"zeta":1,"alpha":[1,2],"\u003ctag\u003e":null}`, string(data))
+
+	var decoded OrderedMap[string, json.RawMessage]
+	assert.NoError(t, json.Unmarshal([]byte(`{"b": {"x": 1}, "a": 2, "c": [], "a": 3}`), &decoded))
+	assert.Equal(t, []string{"b", "a", "c"}, decoded.Keys())
+	a, _ := decoded.Get("a")
+	assert.Equal(t, "3", string(a))
+
+	data, err = json.Marshal(&decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"b":{"x":1},"a":3,"c":[]}`, string(data))
+
+	data, err = json.Marshal(NewOrderedMap[string, int]())
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(data))
+
+	assert.NoError(t, json.Unmarshal([]byte(`null`), &decoded))
+	assert.Equal(t, 0, decoded.Len())
+	assert.Error(t, json.Unmarshal([]byte(`[1]`), &decoded))
+	assert.Error(t, json.Unmarshal([]byte(`{"a":"x"}`), &OrderedMap[string, int]{}))
+}
+
+func TestOrderedMapJSONKeys(t *testing.T) {
+	ints := NewOrderedMap[int, string]()
+	ints.Set(10, "ten")
+	ints.Set(-2, "minus two")
+
+	data, err := json.Marshal(ints)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"10":"ten","-2":"minus two"}`, string(data))
+
+	decoded := NewOrderedMap[int8, string]()
+	assert.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, []int8{10, -2}, decoded.Keys())
+	assert.Error(t, json.Unmarshal([]byte(`{"300":""}`), decoded))
+
+	addrs := NewOrderedMap[netip.Addr, bool]()
+	assert.NoError(t, json.Unmarshal([]byte(`{"10.0.0.2":true,"10.0.0.1":false}`), addrs))
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.2"), netip.MustParseAddr("10.0.0.1")}, addrs.Keys())
+	data, err = json.Marshal(addrs)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"10.0.0.2":true,"10.0.0.1":false}`, string(data))
+
+	type point struct{ x, y int }
+	_, err = json.Marshal(&OrderedMap[point, int]{})
+	assert.NoError(t, err)
+	bad := NewOrderedMap[point, int]()
+	bad.Set(point{1, 2}, 3)
+	_, err = json.Marshal(bad)
+	assert.Error(t, err)
+}
+
+func TestOrderedMapInStruct(t *testing.T) {
+	var config struct {
+		Routes OrderedMap[string, string] `json:"routes"`
+	}
+
+	doc := `{"routes":{"/z":"zed","/a":"ay"}}`
+	assert.NoError(t, json.Unmarshal([]byte(doc), &config))
+	assert.Equal(t, []string{"/z", "/a"}, config.Routes.Keys())
+
+	data, err := json.Marshal(&config)
+	assert.NoError(t, err)
+	assert.Equal(t, doc, string(data))
+
+	data, err = json.Marshal(config)
+	assert.NoError(t, err)
+	assert.Equal(t, doc, string(data))
+}
+
+func TestOrderedMapCopy(t *testing.T) {
+	var m OrderedMap[string, int]
+	m.Set("a", 1)
+
+	c := m
+	c.Set("b", 2)
+	c.MoveToFront("b")
+	assert.Equal(t, []string{"b", "a"}, m.Keys())
+	assert.Equal(t, []string{"b", "a"}, c.Keys())
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, 2, m.Len())
+	k, _, _ := m.Back()
+	assert.Equal(t, "a", k)
+}
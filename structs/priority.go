@@ -0,0 +1,302 @@
+package structs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PriorityItem is a handle to an element of a PriorityQueue, used to change
+// its value or remove it.
+type PriorityItem[T any] struct {
+	value T
+
+	// index is the position in the heap, or -1 once the item has left it.
+	index int
+}
+
+// Value returns the element.
+func (i *PriorityItem[T]) Value() T {
+	return i.value
+}
+
+// PriorityQueue is a binary heap that pops the element for which less
+// reports true against every other element first. Push, Pop, Update and
+// Remove are O(log n). A PriorityQueue is not safe for concurrent use.
+type PriorityQueue[T any] struct {
+	items []*PriorityItem[T]
+	less  func(a, b T) bool
+}
+
+// NewPriorityQueue creates a queue ordered by less.
+func NewPriorityQueue[T any](less func(a, b T) bool) *PriorityQueue[T] {
+	return &PriorityQueue[T]{less: less}
+}
+
+// Push adds v to the queue.
+func (q *PriorityQueue[T]) Push(v T) {
+	q.PushItem(v)
+}
+
+// PushItem adds v to the queue and returns its handle.
+func (q *PriorityQueue[T]) PushItem(v T) *PriorityItem[T] {
+	item := &PriorityItem[T]{value: v, index: len(q.items)}
+	q.items = append(q.items, item)
+	q.up(item.index)
+	return item
+}
+
+// Pop removes and returns the first element.
+func (q *PriorityQueue[T]) Pop() (T, bool) {
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return q.remove(0).value, true
+}
+
+// Peek returns the first element without removing it.
+func (q *PriorityQueue[T]) Peek() (T, bool) {
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return q.items[0].value, true
+}
+
+// Len returns the number of elements.
+func (q *PriorityQueue[T]) Len() int {
+	return len(q.items)
+}
+
+// Update replaces the value of item and restores the order. It reports false
+// if item is no longer in the queue.
+func (q *PriorityQueue[T]) Update(item *PriorityItem[T], v T) bool {
+	if !q.contains(item) {
+		return false
+	}
+
+	item.value = v
+	q.fix(item.index)
+	return true
+}
+
+// Remove takes item out of the queue. It reports false if item is no longer
+// in the queue.
+func (q *PriorityQueue[T]) Remove(item *PriorityItem[T]) bool {
+	if !q.contains(item) {
+		return false
+	}
+
+	q.remove(item.index)
+	return true
+}
+
+// Clear removes all elements; their handles become invalid.
+func (q *PriorityQueue[T]) Clear() {
+	for _, item := range q.items {
+		item.index = -1
+	}
+
+	clear(q.items)
+	q.items = q.items[:0]
+}
+
+func (q *PriorityQueue[T]) contains(item *PriorityItem[T]) bool {
+	return item != nil && item.index >= 0 && item.index < len(q.items) && q.items[item.index] == item
+}
+
+func (q *PriorityQueue[T]) remove(i int) *PriorityItem[T] {
+	item := q.items[i]
+	last := len(q.items) - 1
+	q.swap(i, last)
+	q.items[last] = nil
+	q.items = q.items[:last]
+	if i < last {
+		q.fix(i)
+	}
+
+	item.index = -1
+	return item
+}
+
+func (q *PriorityQueue[T]) fix(i int) {
+	if !q.down(i) {
+		q.up(i)
+	}
+}
+
+func (q *PriorityQueue[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.less(q.items[i].value, q.items[parent].value) {
+			return
+		}
+
+		q.swap(i, parent)
+		i = parent
+	}
+}
+
+// down reports whether the element moved.
+func (q *PriorityQueue[T]) down(i int) bool {
+	start := i
+	for {
+		first := 2*i + 1
+		if first >= len(q.items) {
+			break
+		}
+
+		if right := first + 1; right < len(q.items) && q.less(q.items[right].value, q.items[first].value) {
+			first = right
+		}
+
+		if !q.less(q.items[first].value, q.items[i].value) {
+			break
+		}
+
+		q.swap(i, first)
+		i = first
+	}
+
+	return i > start
+}
+
+func (q *PriorityQueue[T]) swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+type delayed[T any] struct {
+	value T
+	due   time.Time
+	seq   uint64
+}
+
+// DelayQueue holds elements until their due time. Elements become poppable
+// in order of due time, and in push order when due at the same time. A
+// DelayQueue is safe for concurrent use.
+type DelayQueue[T any] struct {
+	mu    sync.Mutex
+	items *PriorityQueue[delayed[T]]
+	seq   uint64
+	clock Clock
+
+	// changed is closed and replaced whenever an element is pushed, waking
+	// waiters whose earliest deadline may have moved.
+	changed chan struct{}
+}
+
+// NewDelayQueue creates an empty DelayQueue. A nil clock means SystemClock.
+func NewDelayQueue[T any](clock Clock) *DelayQueue[T] {
+	return &DelayQueue[T]{
+		items: NewPriorityQueue(func(a, b delayed[T]) bool {
+			if a.due.Equal(b.due) {
+				return a.seq < b.seq
+			}
+
+			return a.due.Before(b.due)
+		}),
+		clock:   clockOrSystem(clock),
+		changed: make(chan struct{}),
+	}
+}
+
+// Push adds v, to become poppable at due.
+func (q *DelayQueue[T]) Push(v T, due time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seq++
+	q.items.Push(delayed[T]{value: v, due: due, seq: q.seq})
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// PushAfter adds v, to become poppable after d.
+func (q *DelayQueue[T]) PushAfter(v T, d time.Duration) {
+	q.Push(v, q.clock.Now().Add(d))
+}
+
+// TryPop removes and returns the earliest element if it is due.
+func (q *DelayQueue[T]) TryPop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	v, _, ok := q.popDue()
+	return v, ok
+}
+
+// Pop removes and returns the earliest element, waiting until it is due or
+// ctx is done.
+func (q *DelayQueue[T]) Pop(ctx context.Context) (T, error) {
+	for {
+		q.mu.Lock()
+		v, wait, ok := q.popDue()
+		changed := q.changed
+		q.mu.Unlock()
+		if ok {
+			return v, nil
+		}
+
+		var timer Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = q.clock.NewTimer(wait)
+			timeout = timer.C()
+		}
+
+		var err error
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-changed:
+		case <-timeout:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err != nil {
+			return v, err
+		}
+	}
+}
+
+// popDue must be called with mu held. When nothing is due it returns the
+// time until the earliest deadline, or zero if the queue is empty.
+func (q *DelayQueue[T]) popDue() (T, time.Duration, bool) {
+	var zero T
+	next, ok := q.items.Peek()
+	if !ok {
+		return zero, 0, false
+	}
+
+	if wait := next.due.Sub(q.clock.Now()); wait > 0 {
+		return zero, wait, false
+	}
+
+	q.items.Pop()
+	return next.value, 0, true
+}
+
+// Peek returns the earliest element and its due time without removing it,
+// whether or not it is due.
+func (q *DelayQueue[T]) Peek() (T, time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	next, ok := q.items.Peek()
+	return next.value, next.due, ok
+}
+
+// Len returns the number of elements, due or not.
+func (q *DelayQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
@@ -0,0 +1,163 @@
+package structs
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yetiz-org/goth-util/internal/clock/clocktest"
+)
+
+func intLess(a, b int) bool { return a < b }
+
+func TestPriorityQueue(t *testing.T) {
+	var q TypedQueue[int] = NewPriorityQueue(intLess)
+	_, ok := q.Pop()
+	assert.False(t, ok)
+
+	values := rand.Perm(200)
+	for _, v := range values {
+		q.Push(v)
+	}
+
+	peeked, _ := q.Peek()
+	assert.Equal(t, 0, peeked)
+	assert.Equal(t, 200, q.Len())
+	for i := 0; i < 200; i++ {
+		v, ok := q.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+}
+
+func TestPriorityQueueUpdateAndRemove(t *testing.T) {
+	q := NewPriorityQueue(func(a, b string) bool { return a > b })
+	a := q.PushItem("a")
+	m := q.PushItem("m")
+	z := q.PushItem("z")
+
+	first, _ := q.Peek()
+	assert.Equal(t, "z", first)
+
+	assert.True(t, q.Update(a, "zz"))
+	first, _ = q.Peek()
+	assert.Equal(t, "zz", first)
+	assert.Equal(t, "zz", a.Value())
+
+	assert.True(t, q.Remove(z))
+	assert.False(t, q.Remove(z))
+	assert.False(t, q.Update(z, "x"))
+
+	v, _ := q.Pop()
+	assert.Equal(t, "zz", v)
+	assert.False(t, q.Remove(a))
+	v, _ = q.Pop()
+	assert.Equal(t, "m", v)
+	assert.False(t, q.Update(m, "n"))
+
+	q.PushItem("b")
+	c := q.PushItem("c")
+	q.Clear()
+	assert.Equal(t, 0, q.Len())
+	assert.False(t, q.Remove(c))
+	assert.False(t, q.Remove(nil))
+}
+
+func TestPriorityQueueRandomOps(t *testing.T) {
+	q := NewPriorityQueue(intLess)
+	handles := map[*PriorityItem[int]]bool{}
+	for i := 0; i < 2000; i++ {
+		switch rand.IntN(4) {
+		case 0, 1:
+			handles[q.PushItem(rand.IntN(1000))] = true
+		case 2:
+			for item := range handles {
+				assert.True(t, q.Update(item, rand.IntN(1000)))
+				break
+			}
+		case 3:
+			for item := range handles {
+				assert.True(t, q.Remove(item))
+				delete(handles, item)
+				break
+			}
+		}
+	}
+
+	var want []int
+	for item := range handles {
+		want = append(want, item.Value())
+	}
+
+	sort.Ints(want)
+	var got []int
+	for v, ok := q.Pop(); ok; v, ok = q.Pop() {
+		got = append(got, v)
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestDelayQueue(t *testing.T) {
+	clock := clocktest.NewManual()
+	q := NewDelayQueue[string](clock)
+
+	_, ok := q.TryPop()
+	assert.False(t, ok)
+
+	q.PushAfter("late", 2*time.Second)
+	q.PushAfter("early", time.Second)
+	q.PushAfter("early-2", time.Second)
+	assert.Equal(t, 3, q.Len())
+
+	v, due, ok := q.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, "early", v)
+	assert.Equal(t, clock.Now().Add(time.Second), due)
+
+	_, ok = q.TryPop()
+	assert.False(t, ok)
+
+	clock.Advance(time.Second)
+	v, _ = q.TryPop()
+	assert.Equal(t, "early", v)
+	v, _ = q.TryPop()
+	assert.Equal(t, "early-2", v)
+	_, ok = q.TryPop()
+	assert.False(t, ok)
+}
+
+func TestDelayQueuePopWaits(t *testing.T) {
+	clock := clocktest.NewManual()
+	q := NewDelayQueue[int](clock)
+
+	popped := make(chan int)
+	go func() {
+		v, err := q.Pop(context.Background())
+		assert.NoError(t, err)
+		popped <- v
+	}()
+
+	// An empty queue waits for a push, then for its deadline.
+	q.PushAfter(1, time.Minute)
+	clock.WaitPending(t, 1)
+
+	// An earlier element wakes the waiter, which re-arms its timer.
+	q.PushAfter(2, time.Second)
+	assert.Eventually(t, func() bool {
+		deadlines := clock.Deadlines()
+		return len(deadlines) == 1 && deadlines[0].Equal(clock.Now().Add(time.Second))
+	}, time.Second, time.Millisecond)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, 2, <-popped)
+	assert.Equal(t, 1, q.Len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := q.Pop(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,6 +1,11 @@
 // Package structs provides common data structure interfaces and implementations.
 package structs
 
+import (
+	"fmt"
+	"reflect"
+)
+
 // Queue defines a basic queue (FIFO - First In, First Out) interface.
 // This interface provides the fundamental operations for queue data structures.
 type Queue interface {
@@ -12,3 +17,60 @@ type Queue interface {
 	// The element v can be of any type.
 	Push(v interface{})
 }
+
+// TypedQueue is the generic form of Queue. Pop and Peek report whether the
+// queue held an element instead of returning nil.
+type TypedQueue[T any] interface {
+	// Push adds v to the back of the queue.
+	Push(v T)
+
+	// Pop removes and returns the element at the front of the queue.
+	Pop() (T, bool)
+
+	// Peek returns the element at the front of the queue without removing it.
+	Peek() (T, bool)
+
+	// Len returns the number of elements in the queue.
+	Len() int
+}
+
+// fifo is the part of TypedQueue that Queue needs.
+type fifo[T any] interface {
+	Push(v T)
+	Pop() (T, bool)
+}
+
+// LegacyQueue adapts a TypedQueue, or any queue with the same Push and Pop
+// methods, to the Queue interface. Pop returns nil when the queue is empty.
+// Push pushes nil as the zero value of T and panics on values of any other
+// type than T, since the Queue interface has no way to report the mismatch.
+func LegacyQueue[T any](q fifo[T]) Queue {
+	return legacyQueue[T]{q: q}
+}
+
+type legacyQueue[T any] struct {
+	q fifo[T]
+}
+
+func (l legacyQueue[T]) Pop() interface{} {
+	if v, ok := l.q.Pop(); ok {
+		return v
+	}
+
+	return nil
+}
+
+func (l legacyQueue[T]) Push(v interface{}) {
+	if v == nil {
+		var zero T
+		l.q.Push(zero)
+		return
+	}
+
+	item, ok := v.(T)
+	if !ok {
+		panic(fmt.Sprintf("structs: LegacyQueue[%s] cannot push value of type %T", reflect.TypeFor[T](), v))
+	}
+
+	l.q.Push(item)
+}
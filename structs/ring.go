@@ -0,0 +1,101 @@
+package structs
+
+import "iter"
+
+// minRingCapacity is the smallest buffer a RingQueue shrinks to.
+const minRingCapacity = 8
+
+// RingQueue is a TypedQueue backed by a growable ring buffer. Push and Pop
+// are amortized O(1). The buffer doubles when full and halves when a quarter
+// full, and removed slots are zeroed so the queue never keeps popped values
+// reachable. A RingQueue is not safe for concurrent use.
+type RingQueue[T any] struct {
+	buf  []T
+	head int
+	size int
+}
+
+// NewRingQueue creates an empty RingQueue with room for capacity elements
+// before it first grows.
+func NewRingQueue[T any](capacity int) *RingQueue[T] {
+	return &RingQueue[T]{buf: make([]T, max(capacity, minRingCapacity))}
+}
+
+// Push adds v to the back of the queue.
+func (q *RingQueue[T]) Push(v T) {
+	if q.size == len(q.buf) {
+		q.resize(max(2*len(q.buf), minRingCapacity))
+	}
+
+	q.buf[(q.head+q.size)%len(q.buf)] = v
+	q.size++
+}
+
+// Pop removes and returns the element at the front of the queue.
+func (q *RingQueue[T]) Pop() (T, bool) {
+	var zero T
+	if q.size == 0 {
+		return zero, false
+	}
+
+	v := q.buf[q.head]
+	q.buf[q.head] = zero
+	q.head = (q.head + 1) % len(q.buf)
+	q.size--
+
+	if len(q.buf) > minRingCapacity && q.size <= len(q.buf)/4 {
+		q.resize(len(q.buf) / 2)
+	}
+
+	return v, true
+}
+
+// Peek returns the element at the front of the queue without removing it.
+func (q *RingQueue[T]) Peek() (T, bool) {
+	if q.size == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return q.buf[q.head], true
+}
+
+// Len returns the number of elements in the queue.
+func (q *RingQueue[T]) Len() int {
+	return q.size
+}
+
+// Cap returns the number of elements the queue holds before it grows.
+func (q *RingQueue[T]) Cap() int {
+	return len(q.buf)
+}
+
+// Clear removes all elements and releases the buffer.
+func (q *RingQueue[T]) Clear() {
+	q.buf = make([]T, minRingCapacity)
+	q.head, q.size = 0, 0
+}
+
+// All returns the elements from front to back without removing them. The
+// queue must not be modified during iteration.
+func (q *RingQueue[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < q.size; i++ {
+			if !yield(q.buf[(q.head+i)%len(q.buf)]) {
+				return
+			}
+		}
+	}
+}
+
+func (q *RingQueue[T]) resize(capacity int) {
+	buf := make([]T, capacity)
+	if q.head+q.size <= len(q.buf) {
+		copy(buf, q.buf[q.head:q.head+q.size])
+	} else {
+		n := copy(buf, q.buf[q.head:])
+		copy(buf[n:], q.buf[:q.size-n])
+	}
+
+	q.buf, q.head = buf, 0
+}
@@ -0,0 +1,137 @@
+package structs
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingQueue(t *testing.T) {
+	var q TypedQueue[int] = NewRingQueue[int](0)
+
+	_, ok := q.Pop()
+	assert.False(t, ok)
+	_, ok = q.Peek()
+	assert.False(t, ok)
+
+	for i := 0; i < 100; i++ {
+		q.Push(i)
+	}
+
+	assert.Equal(t, 100, q.Len())
+	v, ok := q.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, 0, v)
+
+	for i := 0; i < 100; i++ {
+		v, ok := q.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestRingQueueWrapAround(t *testing.T) {
+	q := NewRingQueue[int](4)
+	next, want := 0, 0
+	for round := 0; round < 50; round++ {
+		for i := 0; i < round%7+1; i++ {
+			q.Push(next)
+			next++
+		}
+
+		for i := 0; i < round%5+1 && q.Len() > 0; i++ {
+			v, _ := q.Pop()
+			assert.Equal(t, want, v)
+			want++
+		}
+
+		assert.Equal(t, next-want, q.Len())
+		assert.Equal(t, q.Len(), len(slices.Collect(q.All())))
+	}
+}
+
+func TestRingQueueGrowAndShrink(t *testing.T) {
+	q := NewRingQueue[int](0)
+	assert.Equal(t, minRingCapacity, q.Cap())
+
+	for i := 0; i < 1000; i++ {
+		q.Push(i)
+	}
+
+	assert.Equal(t, 1024, q.Cap())
+	for i := 0; i < 1000; i++ {
+		q.Pop()
+	}
+
+	assert.Equal(t, minRingCapacity, q.Cap())
+
+	q.Push(1)
+	q.Clear()
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, minRingCapacity, q.Cap())
+}
+
+func TestRingQueueAll(t *testing.T) {
+	q := NewRingQueue[string](2)
+	q.Push("a")
+	q.Push("b")
+	q.Pop()
+	q.Push("c")
+	q.Push("d")
+
+	assert.Equal(t, []string{"b", "c", "d"}, slices.Collect(q.All()))
+
+	var first []string
+	for v := range q.All() {
+		first = append(first, v)
+		break
+	}
+
+	assert.Equal(t, []string{"b"}, first)
+	assert.Equal(t, 3, q.Len())
+}
+
+func TestRingQueueReleasesPopped(t *testing.T) {
+	q := NewRingQueue[*int](0)
+	for i := 0; i < 3; i++ {
+		q.Push(new(int))
+	}
+
+	q.Pop()
+	q.Pop()
+	front, _ := q.Peek()
+	for _, slot := range q.buf {
+		if slot != nil {
+			assert.Same(t, front, slot)
+		}
+	}
+
+	q.Clear()
+	assert.Equal(t, make([]*int, minRingCapacity), q.buf)
+}
+
+func TestLegacyQueue(t *testing.T) {
+	var queue Queue = LegacyQueue[string](NewRingQueue[string](0))
+
+	queue.Push("hello")
+	assert.PanicsWithValue(t, "structs: LegacyQueue[string] cannot push value of type int", func() { queue.Push(42) })
+	queue.Push(nil)
+	queue.Push("world")
+
+	assert.Equal(t, "hello", queue.Pop())
+	assert.Equal(t, "", queue.Pop())
+	assert.Equal(t, "world", queue.Pop())
+	assert.Nil(t, queue.Pop())
+}
+
+func BenchmarkRingQueue(b *testing.B) {
+	q := NewRingQueue[int](0)
+	for i := 0; i < b.N; i++ {
+		q.Push(i)
+		q.Push(i)
+		q.Pop()
+	}
+}
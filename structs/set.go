@@ -0,0 +1,390 @@
+package structs
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Set is an unordered collection of distinct values. It is a map, so an
+// existing map[T]struct{} converts to it directly:
+//
+//	codes := structs.Set[string](validate.ExistCountryCodeMap)
+//
+// The zero value is a nil set, which can be read but not added to; use
+// NewSet. A Set is not safe for concurrent use; see SyncSet and FrozenSet.
+type Set[T comparable] map[T]struct{}
+
+// NewSet creates a set holding values.
+func NewSet[T comparable](values ...T) Set[T] {
+	s := make(Set[T], len(values))
+	s.Add(values...)
+	return s
+}
+
+// SetOf collects the values of seq into a new set.
+func SetOf[T comparable](seq iter.Seq[T]) Set[T] {
+	s := Set[T]{}
+	for v := range seq {
+		s[v] = struct{}{}
+	}
+
+	return s
+}
+
+// Add inserts values.
+func (s Set[T]) Add(values ...T) {
+	for _, v := range values {
+		s[v] = struct{}{}
+	}
+}
+
+// Remove deletes values.
+func (s Set[T]) Remove(values ...T) {
+	for _, v := range values {
+		delete(s, v)
+	}
+}
+
+// Contains reports whether v is in the set.
+func (s Set[T]) Contains(v T) bool {
+	_, found := s[v]
+	return found
+}
+
+// Len returns the number of values.
+func (s Set[T]) Len() int {
+	return len(s)
+}
+
+// Clear removes every value.
+func (s Set[T]) Clear() {
+	clear(s)
+}
+
+// Clone returns a copy of the set.
+func (s Set[T]) Clone() Set[T] {
+	c := make(Set[T], len(s))
+	for v := range s {
+		c[v] = struct{}{}
+	}
+
+	return c
+}
+
+// Union returns the values in s or o.
+func (s Set[T]) Union(o Set[T]) Set[T] {
+	u := s.Clone()
+	for v := range o {
+		u[v] = struct{}{}
+	}
+
+	return u
+}
+
+// Intersect returns the values in both s and o.
+func (s Set[T]) Intersect(o Set[T]) Set[T] {
+	small, large := s, o
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+
+	i := Set[T]{}
+	for v := range small {
+		if large.Contains(v) {
+			i[v] = struct{}{}
+		}
+	}
+
+	return i
+}
+
+// Difference returns the values in s but not in o.
+func (s Set[T]) Difference(o Set[T]) Set[T] {
+	d := Set[T]{}
+	for v := range s {
+		if !o.Contains(v) {
+			d[v] = struct{}{}
+		}
+	}
+
+	return d
+}
+
+// SymmetricDifference returns the values in exactly one of s and o.
+func (s Set[T]) SymmetricDifference(o Set[T]) Set[T] {
+	d := s.Difference(o)
+	for v := range o {
+		if !s.Contains(v) {
+			d[v] = struct{}{}
+		}
+	}
+
+	return d
+}
+
+// IsSubsetOf reports whether every value of s is in o.
+func (s Set[T]) IsSubsetOf(o Set[T]) bool {
+	if len(s) > len(o) {
+		return false
+	}
+
+	for v := range s {
+		if !o.Contains(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsSupersetOf reports whether every value of o is in s.
+func (s Set[T]) IsSupersetOf(o Set[T]) bool {
+	return o.IsSubsetOf(s)
+}
+
+// IsProperSubsetOf reports whether s is a subset of o and smaller than it.
+func (s Set[T]) IsProperSubsetOf(o Set[T]) bool {
+	return len(s) < len(o) && s.IsSubsetOf(o)
+}
+
+// IsDisjoint reports whether s and o have no value in common.
+func (s Set[T]) IsDisjoint(o Set[T]) bool {
+	return s.Intersect(o).Len() == 0
+}
+
+// Equal reports whether s and o hold the same values.
+func (s Set[T]) Equal(o Set[T]) bool {
+	return len(s) == len(o) && s.IsSubsetOf(o)
+}
+
+// All returns the values in unspecified order.
+func (s Set[T]) All() iter.Seq[T] {
+	return maps.Keys(s)
+}
+
+// Values returns the values in unspecified order.
+func (s Set[T]) Values() []T {
+	return slices.Collect(maps.Keys(s))
+}
+
+// SortedFunc returns the values ordered by cmp.
+func (s Set[T]) SortedFunc(cmp func(a, b T) int) []T {
+	return slices.SortedFunc(maps.Keys(s), cmp)
+}
+
+// Sorted returns the values of s in ascending order.
+func Sorted[T cmp.Ordered](s Set[T]) []T {
+	return slices.Sorted(maps.Keys(s))
+}
+
+// MarshalJSON encodes the set as an array in a stable order: ascending by
+// value when T is a string, integer or floating-point type, and otherwise
+// by the encoding of the elements.
+func (s Set[T]) MarshalJSON() ([]byte, error) {
+	values := slices.Collect(maps.Keys(s))
+	compare := orderedCompare[T]()
+	if compare != nil {
+		slices.SortFunc(values, compare)
+	}
+
+	encoded := make([][]byte, 0, len(values))
+	for _, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded = append(encoded, b)
+	}
+
+	if compare == nil {
+		slices.SortFunc(encoded, bytes.Compare)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	buf.Write(bytes.Join(encoded, []byte{','}))
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// orderedCompare returns a comparison by value for types whose underlying
+// type is ordered, or nil for other types.
+func orderedCompare[T comparable]() func(a, b T) int {
+	switch reflect.TypeFor[T]().Kind() {
+	case reflect.String:
+		return func(a, b T) int { return cmp.Compare(reflect.ValueOf(a).String(), reflect.ValueOf(b).String()) }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(a, b T) int { return cmp.Compare(reflect.ValueOf(a).Int(), reflect.ValueOf(b).Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(a, b T) int { return cmp.Compare(reflect.ValueOf(a).Uint(), reflect.ValueOf(b).Uint()) }
+	case reflect.Float32, reflect.Float64:
+		return func(a, b T) int { return cmp.Compare(reflect.ValueOf(a).Float(), reflect.ValueOf(b).Float()) }
+	default:
+		return nil
+	}
+}
+
+// UnmarshalJSON decodes an array, replacing the contents of the set.
+// Duplicates collapse; null yields an empty set.
+func (s *Set[T]) UnmarshalJSON(data []byte) error {
+	var values []T
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	*s = NewSet(values...)
+	return nil
+}
+
+// SyncSet is a Set guarded by a read-write mutex, safe for concurrent use.
+// The zero value is an empty set ready to use.
+type SyncSet[T comparable] struct {
+	mu sync.RWMutex
+	s  Set[T]
+}
+
+// NewSyncSet creates a SyncSet holding values.
+func NewSyncSet[T comparable](values ...T) *SyncSet[T] {
+	return &SyncSet[T]{s: NewSet(values...)}
+}
+
+// Add inserts values.
+func (s *SyncSet[T]) Add(values ...T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.s == nil {
+		s.s = Set[T]{}
+	}
+
+	s.s.Add(values...)
+}
+
+// AddIfAbsent inserts v and reports whether it was missing.
+func (s *SyncSet[T]) AddIfAbsent(v T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.s.Contains(v) {
+		return false
+	}
+
+	if s.s == nil {
+		s.s = Set[T]{}
+	}
+
+	s.s[v] = struct{}{}
+	return true
+}
+
+// Remove deletes values.
+func (s *SyncSet[T]) Remove(values ...T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.s.Remove(values...)
+}
+
+// Contains reports whether v is in the set.
+func (s *SyncSet[T]) Contains(v T) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.s.Contains(v)
+}
+
+// Len returns the number of values.
+func (s *SyncSet[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.s)
+}
+
+// Clear removes every value.
+func (s *SyncSet[T]) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.s)
+}
+
+// Snapshot returns a copy of the current values.
+func (s *SyncSet[T]) Snapshot() Set[T] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.s.Clone()
+}
+
+// All returns the values of a snapshot taken when iteration starts.
+func (s *SyncSet[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range s.Snapshot() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON encodes the set as an array.
+func (s *SyncSet[T]) MarshalJSON() ([]byte, error) {
+	return s.Snapshot().MarshalJSON()
+}
+
+// UnmarshalJSON decodes an array, replacing the contents of the set.
+func (s *SyncSet[T]) UnmarshalJSON(data []byte) error {
+	var decoded Set[T]
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.s = decoded
+	return nil
+}
+
+// FrozenSet is an immutable set, safe for concurrent reads without locking.
+// It suits package-level lookup tables.
+type FrozenSet[T comparable] struct {
+	s Set[T]
+}
+
+// Freeze creates a FrozenSet holding values.
+func Freeze[T comparable](values ...T) FrozenSet[T] {
+	return FrozenSet[T]{s: NewSet(values...)}
+}
+
+// Freeze returns an immutable copy of s.
+func (s Set[T]) Freeze() FrozenSet[T] {
+	return FrozenSet[T]{s: s.Clone()}
+}
+
+// Contains reports whether v is in the set.
+func (f FrozenSet[T]) Contains(v T) bool {
+	return f.s.Contains(v)
+}
+
+// Len returns the number of values.
+func (f FrozenSet[T]) Len() int {
+	return len(f.s)
+}
+
+// All returns the values in unspecified order.
+func (f FrozenSet[T]) All() iter.Seq[T] {
+	return f.s.All()
+}
+
+// Set returns a mutable copy.
+func (f FrozenSet[T]) Set() Set[T] {
+	return f.s.Clone()
+}
+
+// MarshalJSON encodes the set as an array.
+func (f FrozenSet[T]) MarshalJSON() ([]byte, error) {
+	return f.s.MarshalJSON()
+}
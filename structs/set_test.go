@@ -0,0 +1,141 @@
+package structs
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	s := NewSet(1, 2, 3, 3)
+	assert.Equal(t, 3, s.Len())
+	assert.True(t, s.Contains(2))
+	assert.False(t, s.Contains(4))
+
+	s.Add(4)
+	s.Remove(1, 9)
+	assert.Equal(t, []int{2, 3, 4}, Sorted(s))
+	assert.ElementsMatch(t, []int{2, 3, 4}, s.Values())
+	assert.ElementsMatch(t, []int{2, 3, 4}, slices.Collect(s.All()))
+	assert.Equal(t, []int{4, 3, 2}, s.SortedFunc(func(a, b int) int { return b - a }))
+
+	c := s.Clone()
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, 3, s.Len())
+
+	var empty Set[int]
+	assert.False(t, empty.Contains(1))
+	assert.Equal(t, 0, empty.Len())
+
+	assert.Equal(t, []string{"a", "b"}, Sorted(SetOf(slices.Values(strings.Split("b,a,b", ",")))))
+	assert.True(t, Set[string](map[string]struct{}{"x": {}}).Contains("x"))
+}
+
+func TestSetAlgebra(t *testing.T) {
+	a, b := NewSet(1, 2, 3), NewSet(3, 4)
+
+	assert.Equal(t, []int{1, 2, 3, 4}, Sorted(a.Union(b)))
+	assert.Equal(t, []int{3}, Sorted(a.Intersect(b)))
+	assert.Equal(t, []int{1, 2}, Sorted(a.Difference(b)))
+	assert.Equal(t, []int{1, 2, 4}, Sorted(a.SymmetricDifference(b)))
+	assert.Equal(t, []int{1, 2, 3}, Sorted(a))
+
+	assert.True(t, NewSet(1, 2).IsSubsetOf(a))
+	assert.True(t, NewSet(1, 2).IsProperSubsetOf(a))
+	assert.True(t, a.IsSubsetOf(a))
+	assert.False(t, a.IsProperSubsetOf(a))
+	assert.False(t, b.IsSubsetOf(a))
+	assert.True(t, a.IsSupersetOf(NewSet(3)))
+	assert.True(t, a.IsDisjoint(NewSet(7)))
+	assert.False(t, a.IsDisjoint(b))
+	assert.True(t, a.Equal(NewSet(3, 2, 1)))
+	assert.False(t, a.Equal(NewSet(1, 2, 4)))
+}
+
+func TestSetJSON(t *testing.T) {
+	data, err := json.Marshal(NewSet("b", "c", "a"))
+	assert.NoError(t, err)
+	assert.Equal(t, `["a","b","c"]`, string(data))
+
+	data, err = json.Marshal(NewSet(10, 2, -1, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, `[-1,1,2,10]`, string(data))
+
+	type point struct{ X int }
+	data, err = json.Marshal(NewSet(point{10}, point{2}))
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"X":10},{"X":2}]`, string(data))
+
+	data, err = json.Marshal(NewSet[int]())
+	assert.NoError(t, err)
+	assert.Equal(t, `[]`, string(data))
+
+	var decoded struct {
+		Tags Set[string] `json:"tags"`
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"tags":["x","y","x"]}`), &decoded))
+	assert.Equal(t, []string{"x", "y"}, Sorted(decoded.Tags))
+	assert.Error(t, json.Unmarshal([]byte(`{"tags":[1]}`), &decoded))
+}
+
+func TestSyncSet(t *testing.T) {
+	var s SyncSet[int]
+	assert.False(t, s.Contains(1))
+	assert.True(t, s.AddIfAbsent(1))
+	assert.False(t, s.AddIfAbsent(1))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s.Add(g*100 + i)
+				s.Contains(i)
+				s.Len()
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	assert.Equal(t, 800, s.Len())
+	assert.Len(t, slices.Collect(s.All()), 800)
+
+	s.Remove(0)
+	assert.Equal(t, 799, s.Snapshot().Len())
+
+	data, err := json.Marshal(NewSyncSet(2, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, `[1,2]`, string(data))
+
+	assert.NoError(t, json.Unmarshal([]byte(`[5]`), &s))
+	assert.Equal(t, 1, s.Len())
+	s.Clear()
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestFrozenSet(t *testing.T) {
+	f := Freeze("a", "b")
+	assert.True(t, f.Contains("a"))
+	assert.Equal(t, 2, f.Len())
+
+	m := f.Set()
+	m.Add("c")
+	assert.False(t, f.Contains("c"))
+
+	source := NewSet(1)
+	frozen := source.Freeze()
+	source.Add(2)
+	assert.Equal(t, 1, frozen.Len())
+	assert.Equal(t, []int{1}, slices.Collect(frozen.All()))
+
+	data, err := json.Marshal(f)
+	assert.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, string(data))
+}
@@ -0,0 +1,446 @@
+package kkutil
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeUnit identifies the resolution of a numeric epoch value.
+type TimeUnit int
+
+const (
+	// UnitAuto asks the parser to detect the unit from the magnitude of the value.
+	UnitAuto TimeUnit = iota
+	// Second is a resolution of one second.
+	Second
+	// Millisecond is a resolution of one millisecond.
+	Millisecond
+	// Microsecond is a resolution of one microsecond.
+	Microsecond
+	// Nanosecond is a resolution of one nanosecond.
+	Nanosecond
+)
+
+// String returns the conventional short name of the unit ("s", "ms", "us", "ns").
+func (u TimeUnit) String() string {
+	switch u {
+	case UnitAuto:
+		return "auto"
+	case Second:
+		return "s"
+	case Millisecond:
+		return "ms"
+	case Microsecond:
+		return "us"
+	case Nanosecond:
+		return "ns"
+	default:
+		return "TimeUnit(" + strconv.Itoa(int(u)) + ")"
+	}
+}
+
+// perSecond returns how many units fit into one second.
+func (u TimeUnit) perSecond() int64 {
+	switch u {
+	case Second:
+		return 1
+	case Millisecond:
+		return 1e3
+	case Microsecond:
+		return 1e6
+	case Nanosecond:
+		return 1e9
+	default:
+		return 0
+	}
+}
+
+var (
+	// ErrTimestampOutOfRange is returned when a timestamp cannot be represented
+	// in the requested unit or storage width.
+	ErrTimestampOutOfRange = errors.New("timestamp out of range")
+
+	// ErrInvalidTimeUnit is returned when an unknown TimeUnit is supplied.
+	ErrInvalidTimeUnit = errors.New("invalid time unit")
+
+	// ErrInvalidTimestamp is returned when a value cannot be parsed as a timestamp.
+	ErrInvalidTimestamp = errors.New("invalid timestamp")
+)
+
+// MaxTimestampUint32 is the last instant that fits in an unsigned 32-bit
+// seconds counter (2106-02-07 06:28:15 UTC).
+var MaxTimestampUint32 = Timestamp{sec: math.MaxUint32}
+
+// MaxTimestampInt32 is the last instant that fits in a signed 32-bit
+// seconds counter (2038-01-19 03:14:07 UTC).
+var MaxTimestampInt32 = Timestamp{sec: math.MaxInt32}
+
+// Timestamp is an instant on the Unix time line stored as whole seconds plus
+// a nanosecond remainder. Unlike a bare integer it carries no implicit unit,
+// so conversions to and from numeric epochs always name the unit explicitly,
+// and narrowing to 32-bit storage is range-checked instead of wrapping.
+//
+// The zero value is the Unix epoch. Timestamp values are comparable with ==.
+type Timestamp struct {
+	sec  int64
+	nsec int32
+}
+
+// TimestampOf builds a Timestamp from a numeric epoch value in the given unit.
+// UnitAuto detects the unit with DetectTimeUnit.
+func TimestampOf(v int64, unit TimeUnit) (Timestamp, error) {
+	if unit == UnitAuto {
+		unit = DetectTimeUnit(v)
+	}
+
+	per := unit.perSecond()
+	if per == 0 {
+		return Timestamp{}, ErrInvalidTimeUnit
+	}
+
+	sec, rem := v/per, v%per
+	if rem < 0 {
+		sec--
+		rem += per
+	}
+
+	return Timestamp{sec: sec, nsec: int32(rem * (1e9 / per))}, nil
+}
+
+// TimestampFromTime converts a time.Time to a Timestamp.
+func TimestampFromTime(t time.Time) Timestamp {
+	return Timestamp{sec: t.Unix(), nsec: int32(t.Nanosecond())}
+}
+
+// TimestampFromUint32 converts an unsigned 32-bit seconds counter to a Timestamp.
+func TimestampFromUint32(sec uint32) Timestamp {
+	return Timestamp{sec: int64(sec)}
+}
+
+// NowTimestamp returns the current time as a Timestamp.
+func NowTimestamp() Timestamp {
+	return TimestampFromTime(time.Now())
+}
+
+// DetectTimeUnit guesses the unit of a numeric epoch value from its magnitude.
+// Values below 1e11 are treated as seconds (good until the year 5138), below
+// 1e14 as milliseconds, below 1e17 as microseconds and anything larger as
+// nanoseconds. Every unit is unambiguous for instants between 1973 and 5138.
+func DetectTimeUnit(v int64) TimeUnit {
+	abs := uint64(v)
+	if v < 0 {
+		abs = uint64(-(v + 1)) + 1
+	}
+
+	switch {
+	case abs < 1e11:
+		return Second
+	case abs < 1e14:
+		return Millisecond
+	case abs < 1e17:
+		return Microsecond
+	default:
+		return Nanosecond
+	}
+}
+
+// ParseTimestamp parses s as a timestamp. Integer and decimal strings are
+// treated as numeric epochs with the unit detected by DetectTimeUnit; any
+// other input is parsed as RFC 3339.
+func ParseTimestamp(s string) (Timestamp, error) {
+	return ParseTimestampIn(s, UnitAuto)
+}
+
+// ParseTimestampIn parses s as a timestamp, reading numeric epochs in unit.
+// Non-numeric input is parsed as RFC 3339 regardless of unit.
+func ParseTimestampIn(s string, unit TimeUnit) (Timestamp, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Timestamp{}, ErrInvalidTimestamp
+	}
+
+	if isNumeric(s) {
+		return parseNumericTimestamp(s, unit)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return Timestamp{}, fmt.Errorf("%w: %q", ErrInvalidTimestamp, s)
+	}
+
+	return TimestampFromTime(t), nil
+}
+
+func isNumeric(s string) bool {
+	if s[0] == '-' || s[0] == '+' {
+		s = s[1:]
+	}
+
+	if s == "" {
+		return false
+	}
+
+	dot := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '.' && !dot:
+			dot = true
+		case c < '0' || c > '9':
+			return false
+		}
+	}
+
+	return s != "."
+}
+
+func parseNumericTimestamp(s string, unit TimeUnit) (Timestamp, error) {
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" || intPart == "-" || intPart == "+" {
+		intPart += "0"
+	}
+
+	v, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return Timestamp{}, fmt.Errorf("%w: %q", ErrTimestampOutOfRange, s)
+	}
+
+	if unit == UnitAuto {
+		unit = DetectTimeUnit(v)
+	}
+
+	ts, err := TimestampOf(v, unit)
+	if err != nil || fracPart == "" {
+		return ts, err
+	}
+
+	// The fractional digits are a fraction of one unit; scale them to nanoseconds.
+	if len(fracPart) > 9 {
+		fracPart = fracPart[:9]
+	}
+
+	frac, _ := strconv.ParseInt(fracPart+strings.Repeat("0", 9-len(fracPart)), 10, 64)
+	frac /= unit.perSecond()
+	if strings.HasPrefix(s, "-") {
+		frac = -frac
+	}
+
+	return ts.Add(time.Duration(frac)), nil
+}
+
+// Time returns the timestamp as a time.Time in the local time zone.
+func (t Timestamp) Time() time.Time {
+	return time.Unix(t.sec, int64(t.nsec))
+}
+
+// UTC returns the timestamp as a time.Time in UTC.
+func (t Timestamp) UTC() time.Time {
+	return t.Time().UTC()
+}
+
+// IsZero reports whether t is the Unix epoch.
+func (t Timestamp) IsZero() bool {
+	return t.sec == 0 && t.nsec == 0
+}
+
+// Before reports whether t is before u.
+func (t Timestamp) Before(u Timestamp) bool {
+	return t.sec < u.sec || (t.sec == u.sec && t.nsec < u.nsec)
+}
+
+// After reports whether t is after u.
+func (t Timestamp) After(u Timestamp) bool {
+	return u.Before(t)
+}
+
+// Add returns t+d.
+func (t Timestamp) Add(d time.Duration) Timestamp {
+	sec := t.sec + int64(d/time.Second)
+	nsec := int64(t.nsec) + int64(d%time.Second)
+	if nsec >= 1e9 {
+		sec++
+		nsec -= 1e9
+	} else if nsec < 0 {
+		sec--
+		nsec += 1e9
+	}
+
+	return Timestamp{sec: sec, nsec: int32(nsec)}
+}
+
+// Sub returns the duration t-u, saturating at the bounds of time.Duration.
+func (t Timestamp) Sub(u Timestamp) time.Duration {
+	return t.Time().Sub(u.Time())
+}
+
+// Unix returns t as whole seconds since the epoch, rounding toward negative infinity.
+func (t Timestamp) Unix() int64 {
+	return t.sec
+}
+
+// Nanosecond returns the sub-second part of t in nanoseconds, in the range [0, 999999999].
+func (t Timestamp) Nanosecond() int {
+	return int(t.nsec)
+}
+
+// In returns t as a numeric epoch in unit, truncating toward negative infinity.
+// It returns ErrTimestampOutOfRange if the value does not fit in an int64,
+// which happens for nanoseconds outside the years 1677 to 2262.
+func (t Timestamp) In(unit TimeUnit) (int64, error) {
+	per := unit.perSecond()
+	if per == 0 {
+		return 0, ErrInvalidTimeUnit
+	}
+
+	if t.sec > math.MaxInt64/per || t.sec < math.MinInt64/per {
+		return 0, ErrTimestampOutOfRange
+	}
+
+	hi := t.sec * per
+	lo := int64(t.nsec) / (1e9 / per)
+	if hi > math.MaxInt64-lo {
+		return 0, ErrTimestampOutOfRange
+	}
+
+	return hi + lo, nil
+}
+
+// Uint32 narrows t to an unsigned 32-bit seconds counter. It returns
+// ErrTimestampOutOfRange for instants before 1970 or after MaxTimestampUint32.
+// The sub-second part is discarded.
+func (t Timestamp) Uint32() (uint32, error) {
+	if t.sec < 0 || t.sec > math.MaxUint32 {
+		return 0, ErrTimestampOutOfRange
+	}
+
+	return uint32(t.sec), nil
+}
+
+// Int32 narrows t to a signed 32-bit seconds counter. It returns
+// ErrTimestampOutOfRange for instants outside 1901-12-13 to MaxTimestampInt32.
+// The sub-second part is discarded.
+func (t Timestamp) Int32() (int32, error) {
+	if t.sec < math.MinInt32 || t.sec > math.MaxInt32 {
+		return 0, ErrTimestampOutOfRange
+	}
+
+	return int32(t.sec), nil
+}
+
+// String returns t formatted as RFC 3339 with nanosecond precision in UTC.
+func (t Timestamp) String() string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// MarshalJSON encodes t as a JSON number of seconds since the epoch. A
+// fractional part is written only when t has a sub-second component.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return t.appendSeconds(nil), nil
+}
+
+func (t Timestamp) appendSeconds(buf []byte) []byte {
+	if t.nsec == 0 {
+		return strconv.AppendInt(buf, t.sec, 10)
+	}
+
+	sec, nsec := t.sec, int64(t.nsec)
+	if sec < 0 {
+		// Render -1.5s rather than -2 + 0.5s.
+		buf = append(buf, '-')
+		sec, nsec = -(sec + 1), 1e9-nsec
+	}
+
+	buf = strconv.AppendUint(buf, uint64(sec), 10)
+	frac := strconv.AppendInt(nil, 1e9+nsec, 10)[1:]
+	frac = bytes.TrimRight(frac, "0")
+	buf = append(buf, '.')
+	return append(buf, frac...)
+}
+
+// UnmarshalJSON decodes a JSON number or string. Numbers and numeric strings
+// are read as epochs with the unit detected by DetectTimeUnit, so clients may
+// send seconds, milliseconds, microseconds or nanoseconds. Other strings are
+// parsed as RFC 3339. JSON null leaves t unchanged.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+
+	ts, err := ParseTimestamp(string(data))
+	if err != nil {
+		return err
+	}
+
+	*t = ts
+	return nil
+}
+
+// MarshalText encodes t as RFC 3339 with nanosecond precision in UTC.
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes text in any format accepted by ParseTimestamp.
+func (t *Timestamp) UnmarshalText(text []byte) error {
+	ts, err := ParseTimestamp(string(text))
+	if err != nil {
+		return err
+	}
+
+	*t = ts
+	return nil
+}
+
+// Value implements driver.Valuer, storing t as a time.Time in UTC.
+func (t Timestamp) Value() (driver.Value, error) {
+	return t.UTC(), nil
+}
+
+// Scan implements sql.Scanner. It accepts time.Time, integer epochs (unit
+// detected by DetectTimeUnit), floating point seconds, and textual values in
+// any format accepted by ParseTimestamp. SQL NULL resets t to the epoch.
+func (t *Timestamp) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Timestamp{}
+		return nil
+	case time.Time:
+		*t = TimestampFromTime(v)
+		return nil
+	case int64:
+		ts, err := TimestampOf(v, UnitAuto)
+		if err != nil {
+			return err
+		}
+
+		*t = ts
+		return nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return ErrInvalidTimestamp
+		}
+
+		ts, err := parseNumericTimestamp(strconv.FormatFloat(v, 'f', -1, 64), Second)
+		if err != nil {
+			return err
+		}
+
+		*t = ts
+		return nil
+	case []byte:
+		return t.UnmarshalText(v)
+	case string:
+		return t.UnmarshalText([]byte(v))
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidTimestamp, src)
+	}
+}
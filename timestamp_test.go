@@ -0,0 +1,217 @@
+package kkutil
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampOf(t *testing.T) {
+	ts, err := TimestampOf(1700000000, Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1700000000), ts.Unix())
+	assert.Equal(t, 0, ts.Nanosecond())
+
+	ts, err = TimestampOf(1700000000123, Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1700000000), ts.Unix())
+	assert.Equal(t, 123000000, ts.Nanosecond())
+
+	ts, err = TimestampOf(-1500, Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(0, -1500*int64(time.Millisecond)).UnixNano(), ts.Time().UnixNano())
+
+	_, err = TimestampOf(1, TimeUnit(42))
+	assert.ErrorIs(t, err, ErrInvalidTimeUnit)
+}
+
+func TestDetectTimeUnit(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, Second, DetectTimeUnit(now.Unix()))
+	assert.Equal(t, Millisecond, DetectTimeUnit(now.UnixMilli()))
+	assert.Equal(t, Microsecond, DetectTimeUnit(now.UnixMicro()))
+	assert.Equal(t, Nanosecond, DetectTimeUnit(now.UnixNano()))
+	assert.Equal(t, Second, DetectTimeUnit(0))
+	assert.Equal(t, Millisecond, DetectTimeUnit(-now.UnixMilli()))
+	assert.Equal(t, Nanosecond, DetectTimeUnit(math.MinInt64))
+
+	for _, v := range []int64{now.Unix(), now.UnixMilli(), now.UnixMicro(), now.UnixNano()} {
+		ts, err := TimestampOf(v, UnitAuto)
+		assert.NoError(t, err)
+		assert.True(t, now.Equal(ts.Time()))
+	}
+}
+
+func TestTimestampIn(t *testing.T) {
+	ts, _ := TimestampOf(1700000000123456789, Nanosecond)
+	for unit, expected := range map[TimeUnit]int64{
+		Second:      1700000000,
+		Millisecond: 1700000000123,
+		Microsecond: 1700000000123456,
+		Nanosecond:  1700000000123456789,
+	} {
+		v, err := ts.In(unit)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, v, unit.String())
+	}
+
+	far := TimestampFromTime(time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC))
+	_, err := far.In(Nanosecond)
+	assert.ErrorIs(t, err, ErrTimestampOutOfRange)
+	_, err = far.In(Microsecond)
+	assert.NoError(t, err)
+
+	_, err = ts.In(UnitAuto)
+	assert.ErrorIs(t, err, ErrInvalidTimeUnit)
+}
+
+func TestTimestampNarrowing(t *testing.T) {
+	v, err := MaxTimestampUint32.Uint32()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(math.MaxUint32), v)
+	assert.Equal(t, MaxTimestamp(), MaxTimestampUint32.Unix())
+	assert.Equal(t, "2106-02-07T06:28:15Z", MaxTimestampUint32.String())
+	assert.Equal(t, MaxTimestampUint32, TimestampFromUint32(math.MaxUint32))
+
+	_, err = MaxTimestampUint32.Add(time.Second).Uint32()
+	assert.ErrorIs(t, err, ErrTimestampOutOfRange)
+	_, err = TimestampFromTime(time.Unix(-1, 0)).Uint32()
+	assert.ErrorIs(t, err, ErrTimestampOutOfRange)
+
+	i, err := MaxTimestampInt32.Int32()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(math.MaxInt32), i)
+	assert.Equal(t, "2038-01-19T03:14:07Z", MaxTimestampInt32.String())
+
+	_, err = MaxTimestampInt32.Add(time.Second).Int32()
+	assert.ErrorIs(t, err, ErrTimestampOutOfRange)
+	_, err = MaxTimestampUint32.Int32()
+	assert.ErrorIs(t, err, ErrTimestampOutOfRange)
+}
+
+func TestTimestampArithmetic(t *testing.T) {
+	a, _ := TimestampOf(1000, Second)
+	b := a.Add(1500 * time.Millisecond)
+	assert.True(t, a.Before(b))
+	assert.True(t, b.After(a))
+	assert.Equal(t, 1500*time.Millisecond, b.Sub(a))
+	assert.Equal(t, a, b.Add(-1500*time.Millisecond))
+	assert.True(t, Timestamp{}.IsZero())
+	assert.False(t, a.IsZero())
+}
+
+func TestParseTimestamp(t *testing.T) {
+	cases := map[string]time.Time{
+		"1700000000":             time.Unix(1700000000, 0),
+		"1700000000.25":          time.Unix(1700000000, 250000000),
+		"1700000000250":          time.Unix(1700000000, 250000000),
+		"1700000000250.5":        time.Unix(1700000000, 250500000),
+		"1700000000250000":       time.Unix(1700000000, 250000000),
+		"1700000000250000001":    time.Unix(1700000000, 250000001),
+		"-1.5":                   time.Unix(-2, 500000000),
+		" 42 ":                   time.Unix(42, 0),
+		"2023-11-14T22:13:20Z":   time.Unix(1700000000, 0),
+		"2023-11-14T22:13:20.5Z": time.Unix(1700000000, 500000000),
+	}
+
+	for in, expected := range cases {
+		ts, err := ParseTimestamp(in)
+		assert.NoError(t, err, in)
+		assert.True(t, expected.Equal(ts.Time()), in)
+	}
+
+	for _, in := range []string{"", "abc", ".", "-", "1.2.3", "2023-13-01T00:00:00Z"} {
+		_, err := ParseTimestamp(in)
+		assert.ErrorIs(t, err, ErrInvalidTimestamp, in)
+	}
+
+	_, err := ParseTimestamp("99999999999999999999")
+	assert.ErrorIs(t, err, ErrTimestampOutOfRange)
+
+	ts, err := ParseTimestampIn("1700000000000", Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1700000000000), ts.Unix())
+}
+
+func TestTimestampJSON(t *testing.T) {
+	ts, _ := TimestampOf(1700000000, Second)
+	data, err := json.Marshal(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, "1700000000", string(data))
+
+	ts = ts.Add(250 * time.Millisecond)
+	data, err = json.Marshal(ts)
+	assert.NoError(t, err)
+	assert.Equal(t, "1700000000.25", string(data))
+
+	neg := TimestampFromTime(time.Unix(-2, 500000000))
+	data, err = json.Marshal(neg)
+	assert.NoError(t, err)
+	assert.Equal(t, "-1.5", string(data))
+
+	var decoded struct {
+		A Timestamp `json:"a"`
+		B Timestamp `json:"b"`
+		C Timestamp `json:"c"`
+		D Timestamp `json:"d"`
+	}
+	err = json.Unmarshal([]byte(`{"a":1700000000250,"b":"1700000000.25","c":"2023-11-14T22:13:20.25Z","d":null}`), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, ts, decoded.A)
+	assert.Equal(t, ts, decoded.B)
+	assert.Equal(t, ts, decoded.C)
+	assert.True(t, decoded.D.IsZero())
+
+	var back Timestamp
+	assert.NoError(t, json.Unmarshal(data, &back))
+	assert.Equal(t, neg, back)
+
+	assert.Error(t, json.Unmarshal([]byte(`"nope"`), &back))
+}
+
+func TestTimestampText(t *testing.T) {
+	ts, _ := TimestampOf(1700000000123, Millisecond)
+	text, err := ts.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-11-14T22:13:20.123Z", string(text))
+
+	var back Timestamp
+	assert.NoError(t, back.UnmarshalText(text))
+	assert.Equal(t, ts, back)
+}
+
+func TestTimestampSQL(t *testing.T) {
+	ts, _ := TimestampOf(1700000000, Second)
+	v, err := ts.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), v)
+
+	var scanned Timestamp
+	assert.NoError(t, scanned.Scan(v))
+	assert.Equal(t, ts, scanned)
+
+	assert.NoError(t, scanned.Scan(int64(1700000000000)))
+	assert.Equal(t, ts, scanned)
+
+	assert.NoError(t, scanned.Scan(1700000000.5))
+	assert.Equal(t, ts.Add(500*time.Millisecond), scanned)
+
+	// Floats are always seconds, even when they look like milliseconds.
+	assert.NoError(t, scanned.Scan(float64(1700000000000)))
+	assert.Equal(t, int64(1700000000000), scanned.Unix())
+
+	assert.NoError(t, scanned.Scan([]byte("1700000000")))
+	assert.Equal(t, ts, scanned)
+
+	assert.NoError(t, scanned.Scan("2023-11-14T22:13:20Z"))
+	assert.Equal(t, ts, scanned)
+
+	assert.NoError(t, scanned.Scan(nil))
+	assert.True(t, scanned.IsZero())
+
+	assert.ErrorIs(t, scanned.Scan(true), ErrInvalidTimestamp)
+	assert.ErrorIs(t, scanned.Scan(math.NaN()), ErrInvalidTimestamp)
+}
@@ -31,18 +31,24 @@ func ZeroTimestamp() int64 {
 
 // MaxTimestamp returns the maximum timestamp value for 32-bit systems (2^32 - 1).
 // This represents the maximum date that can be stored in a 32-bit Unix timestamp.
+//
+// Deprecated: Use MaxTimestampUint32 and Timestamp.Uint32, which range-check
+// instead of relying on callers to compare against a magic number.
 func MaxTimestamp() int64 {
 	return 4294967295
 }
 
 // MaxTimestamp32u returns the maximum timestamp value as an unsigned integer.
 // This is equivalent to MaxTimestamp but returns uint type instead of int64.
+//
+// Deprecated: Use MaxTimestampUint32 and Timestamp.Uint32.
 func MaxTimestamp32u() uint {
 	return 4294967295
 }
 
 // UnixToTime converts a Unix timestamp (seconds since epoch) to a time.Time object.
 // The nanosecond component is set to 0.
+// Use TimestampOf to convert epochs in other units.
 func UnixToTime(unixSecond int64) time.Time {
 	return time.Unix(unixSecond, 0)
 }
@@ -77,6 +83,9 @@ func NowUInt() uint {
 // It handles both IPv4 and IPv6 addresses with proper bracket notation for IPv6.
 // Returns the parsed IP address and port string. If no port is found, port will be empty.
 // Optimized version with reduced string operations and single-pass parsing.
+//
+// Deprecated: Use ParseRemoteAddr, which validates the port, keeps IPv6 zones
+// and reports why an address could not be parsed.
 func SplitRemoteAddr(addr string) (ip net.IP, port string) {
 	if addr == "" {
 		return nil, ""
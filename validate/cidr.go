@@ -0,0 +1,259 @@
+package validate
+
+import (
+	"errors"
+	"iter"
+	"math/big"
+	"net/netip"
+	"sort"
+)
+
+var (
+	// ErrInvalidPrefix is returned for zero or malformed prefixes and addresses.
+	ErrInvalidPrefix = errors.New("invalid prefix")
+
+	// ErrInvalidRange is returned when a range mixes address families or ends before it starts.
+	ErrInvalidRange = errors.New("invalid address range")
+
+	// ErrTooManyPrefixes is returned when SplitPrefix would produce more than MaxSplitPrefixes results.
+	ErrTooManyPrefixes = errors.New("too many prefixes")
+)
+
+// MaxSplitPrefixes bounds the number of subnets SplitPrefix returns.
+var MaxSplitPrefixes = 1 << 16
+
+// ipRange is an inclusive range of addresses of a single family.
+type ipRange struct {
+	first, last netip.Addr
+}
+
+// ParsePrefixes parses a list of CIDR strings such as "10.0.0.0/8".
+func ParsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
+}
+
+// MergePrefixes collapses overlapping and adjacent prefixes into the smallest
+// equivalent list, sorted with IPv4 before IPv6. Invalid prefixes are dropped.
+//
+// For example 10.0.0.0/25, 10.0.0.128/25 and 10.0.0.7/32 merge into 10.0.0.0/24.
+func MergePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	return rangesToPrefixes(prefixesToRanges(prefixes))
+}
+
+// SubtractPrefixes returns the addresses covered by from but not by remove,
+// as a minimal sorted prefix list.
+func SubtractPrefixes(from, remove []netip.Prefix) []netip.Prefix {
+	keep, drop := prefixesToRanges(from), prefixesToRanges(remove)
+
+	var result []ipRange
+	j := 0
+	for _, r := range keep {
+		// Skip removals that end before this range starts.
+		for j < len(drop) && drop[j].last.Less(r.first) {
+			j++
+		}
+
+		cur := r
+		for k := j; k < len(drop) && !r.last.Less(drop[k].first); k++ {
+			d := drop[k]
+			if cur.first.Less(d.first) {
+				result = append(result, ipRange{cur.first, d.first.Prev()})
+			}
+
+			if !d.last.Less(cur.last) {
+				cur.first = netip.Addr{}
+				break
+			}
+
+			if !d.last.Less(cur.first) {
+				cur.first = d.last.Next()
+			}
+		}
+
+		if cur.first.IsValid() {
+			result = append(result, cur)
+		}
+	}
+
+	return rangesToPrefixes(result)
+}
+
+// SplitPrefix divides prefix into subnets of length bits, in address order.
+// It fails if bits is shorter than the prefix, longer than the address, or
+// would yield more than MaxSplitPrefixes subnets.
+func SplitPrefix(prefix netip.Prefix, bits int) ([]netip.Prefix, error) {
+	if !prefix.IsValid() {
+		return nil, ErrInvalidPrefix
+	}
+
+	prefix = prefix.Masked()
+	if bits < prefix.Bits() || bits > prefix.Addr().BitLen() {
+		return nil, ErrInvalidPrefix
+	}
+
+	if bits-prefix.Bits() >= 31 || 1<<(bits-prefix.Bits()) > MaxSplitPrefixes {
+		return nil, ErrTooManyPrefixes
+	}
+
+	subnets := make([]netip.Prefix, 0, 1<<(bits-prefix.Bits()))
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); {
+		subnet := netip.PrefixFrom(addr, bits)
+		subnets = append(subnets, subnet)
+		addr = lastAddr(subnet).Next()
+	}
+
+	return subnets, nil
+}
+
+// RangeToPrefixes returns the minimal list of prefixes covering exactly the
+// addresses from first to last inclusive.
+func RangeToPrefixes(first, last netip.Addr) ([]netip.Prefix, error) {
+	if !first.IsValid() || !last.IsValid() {
+		return nil, ErrInvalidPrefix
+	}
+
+	first, last = first.Unmap().WithZone(""), last.Unmap().WithZone("")
+	if first.Is4() != last.Is4() || last.Less(first) {
+		return nil, ErrInvalidRange
+	}
+
+	return rangesToPrefixes([]ipRange{{first, last}}), nil
+}
+
+// PrefixRange returns the first and last address of prefix.
+func PrefixRange(prefix netip.Prefix) (first, last netip.Addr) {
+	prefix = prefix.Masked()
+	return prefix.Addr(), lastAddr(prefix)
+}
+
+// PrefixAddrs iterates over every address of prefix in order, starting with
+// the network address. Large IPv6 prefixes yield addresses until the loop stops.
+func PrefixAddrs(prefix netip.Prefix) iter.Seq[netip.Addr] {
+	return func(yield func(netip.Addr) bool) {
+		if !prefix.IsValid() {
+			return
+		}
+
+		prefix = prefix.Masked()
+		for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+			if !yield(addr) {
+				return
+			}
+		}
+	}
+}
+
+// PrefixSize returns the number of addresses in prefix, which may exceed
+// 64 bits for IPv6. It returns zero for an invalid prefix.
+func PrefixSize(prefix netip.Prefix) *big.Int {
+	if !prefix.IsValid() {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Lsh(big.NewInt(1), uint(prefix.Addr().BitLen()-prefix.Bits()))
+}
+
+// PrefixesSize returns the number of distinct addresses covered by prefixes.
+func PrefixesSize(prefixes []netip.Prefix) *big.Int {
+	total := new(big.Int)
+	for _, prefix := range MergePrefixes(prefixes) {
+		total.Add(total, PrefixSize(prefix))
+	}
+
+	return total
+}
+
+// prefixesToRanges converts prefixes to sorted, merged address ranges.
+func prefixesToRanges(prefixes []netip.Prefix) []ipRange {
+	ranges := make([]ipRange, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if !prefix.IsValid() {
+			continue
+		}
+
+		prefix = normalizePrefix(prefix)
+		ranges = append(ranges, ipRange{prefix.Addr(), lastAddr(prefix)})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].first.Less(ranges[j].first)
+	})
+
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && merged[n-1].first.Is4() == r.first.Is4() {
+			top := &merged[n-1]
+			next := top.last.Next()
+			if !r.first.Less(top.first) && (!next.IsValid() || !next.Less(r.first)) {
+				if top.last.Less(r.last) {
+					top.last = r.last
+				}
+
+				continue
+			}
+		}
+
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// rangesToPrefixes converts sorted ranges into a minimal prefix list by
+// repeatedly taking the largest aligned block that starts at the range start.
+func rangesToPrefixes(ranges []ipRange) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, r := range ranges {
+		for addr := r.first; addr.IsValid() && !r.last.Less(addr); {
+			bits := 0
+			for ; bits < addr.BitLen(); bits++ {
+				block := netip.PrefixFrom(addr, bits)
+				if block.Masked().Addr() == addr && !r.last.Less(lastAddr(block)) {
+					break
+				}
+			}
+
+			block := netip.PrefixFrom(addr, bits)
+			prefixes = append(prefixes, block)
+			addr = lastAddr(block).Next()
+		}
+	}
+
+	return prefixes
+}
+
+// lastAddr returns the highest address of prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr()
+	if addr.Is4() {
+		b := addr.As4()
+		setHostBits(b[:], prefix.Bits())
+		return netip.AddrFrom4(b)
+	}
+
+	b := addr.As16()
+	setHostBits(b[:], prefix.Bits())
+	return netip.AddrFrom16(b)
+}
+
+func setHostBits(b []byte, prefixBits int) {
+	for i := range b {
+		switch {
+		case prefixBits >= 8*(i+1):
+		case prefixBits <= 8*i:
+			b[i] = 0xff
+		default:
+			b[i] |= 0xff >> (prefixBits - 8*i)
+		}
+	}
+}
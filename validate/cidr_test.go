@@ -0,0 +1,151 @@
+package validate
+
+import (
+	"math/big"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func prefixes(cidrs ...string) []netip.Prefix {
+	result, err := ParsePrefixes(cidrs)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+func TestParsePrefixes(t *testing.T) {
+	_, err := ParsePrefixes([]string{"10.0.0.0/8", "bogus"})
+	assert.Error(t, err)
+}
+
+func TestMergePrefixes(t *testing.T) {
+	assert.Equal(t, prefixes("10.0.0.0/24"),
+		MergePrefixes(prefixes("10.0.0.0/25", "10.0.0.128/25", "10.0.0.7/32")))
+
+	assert.Equal(t, prefixes("10.0.0.0/23", "10.0.2.0/24"),
+		MergePrefixes(prefixes("10.0.2.0/24", "10.0.1.0/24", "10.0.0.0/24")))
+
+	// Overlap, unaligned host bits, and mixed families
+	assert.Equal(t, prefixes("192.168.0.0/16", "2001:db8::/31", "fe80::/10"),
+		MergePrefixes(prefixes("fe80::1/10", "2001:db9::/32", "192.168.3.4/24", "2001:db8::/32", "192.168.0.0/16")))
+
+	// IPv4-mapped prefixes are treated as IPv4
+	assert.Equal(t, prefixes("10.0.0.0/8"), MergePrefixes(prefixes("::ffff:10.0.0.0/104", "10.0.0.0/9")))
+
+	// The top of the address space merges without overflow
+	assert.Equal(t, prefixes("255.255.255.254/31"), MergePrefixes(prefixes("255.255.255.255/32", "255.255.255.254/32")))
+	assert.Equal(t, prefixes("0.0.0.0/0", "::/0"), MergePrefixes(prefixes("::/1", "8000::/1", "0.0.0.0/1", "128.0.0.0/1")))
+
+	assert.Empty(t, MergePrefixes(nil))
+	assert.Empty(t, MergePrefixes([]netip.Prefix{{}}))
+}
+
+func TestSubtractPrefixes(t *testing.T) {
+	assert.Equal(t, prefixes("10.0.0.0/25", "10.0.0.128/26", "10.0.0.192/27", "10.0.0.240/28"),
+		SubtractPrefixes(prefixes("10.0.0.0/24"), prefixes("10.0.0.224/28")))
+
+	assert.Equal(t, prefixes("10.0.0.0/9", "10.128.0.0/10", "10.224.0.0/11"),
+		SubtractPrefixes(prefixes("10.0.0.0/8"), prefixes("10.192.0.0/11")))
+
+	assert.Equal(t, prefixes("10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/32", "10.0.0.6/32"),
+		SubtractPrefixes(prefixes("10.0.0.0/29"), prefixes("10.0.0.0/32", "10.0.0.5/32", "10.0.0.7/32", "2001:db8::/32")))
+
+	assert.Empty(t, SubtractPrefixes(prefixes("10.0.0.0/24"), prefixes("10.0.0.0/8")))
+	assert.Equal(t, prefixes("10.0.0.0/24", "::/1"),
+		SubtractPrefixes(prefixes("10.0.0.0/24", "::/0"), prefixes("8000::/1", "11.0.0.0/8")))
+}
+
+func TestSplitPrefix(t *testing.T) {
+	subnets, err := SplitPrefix(netip.MustParsePrefix("10.0.0.0/24"), 26)
+	assert.NoError(t, err)
+	assert.Equal(t, prefixes("10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26"), subnets)
+
+	subnets, err = SplitPrefix(netip.MustParsePrefix("2001:db8::1/126"), 128)
+	assert.NoError(t, err)
+	assert.Equal(t, prefixes("2001:db8::/128", "2001:db8::1/128", "2001:db8::2/128", "2001:db8::3/128"), subnets)
+
+	subnets, err = SplitPrefix(netip.MustParsePrefix("255.255.255.0/24"), 25)
+	assert.NoError(t, err)
+	assert.Equal(t, prefixes("255.255.255.0/25", "255.255.255.128/25"), subnets)
+
+	_, err = SplitPrefix(netip.MustParsePrefix("10.0.0.0/24"), 23)
+	assert.ErrorIs(t, err, ErrInvalidPrefix)
+	_, err = SplitPrefix(netip.MustParsePrefix("10.0.0.0/24"), 33)
+	assert.ErrorIs(t, err, ErrInvalidPrefix)
+	_, err = SplitPrefix(netip.Prefix{}, 8)
+	assert.ErrorIs(t, err, ErrInvalidPrefix)
+	_, err = SplitPrefix(netip.MustParsePrefix("2001:db8::/32"), 64)
+	assert.ErrorIs(t, err, ErrTooManyPrefixes)
+}
+
+func TestRangeToPrefixes(t *testing.T) {
+	result, err := RangeToPrefixes(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.10"))
+	assert.NoError(t, err)
+	assert.Equal(t, prefixes("10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/30", "10.0.0.8/31", "10.0.0.10/32"), result)
+
+	result, err = RangeToPrefixes(netip.MustParseAddr("0.0.0.0"), netip.MustParseAddr("255.255.255.255"))
+	assert.NoError(t, err)
+	assert.Equal(t, prefixes("0.0.0.0/0"), result)
+
+	result, err = RangeToPrefixes(netip.MustParseAddr("::"), netip.MustParseAddr("::1"))
+	assert.NoError(t, err)
+	assert.Equal(t, prefixes("::/127"), result)
+
+	result, err = RangeToPrefixes(netip.MustParseAddr("::ffff:10.0.0.0"), netip.MustParseAddr("10.0.0.0"))
+	assert.NoError(t, err)
+	assert.Equal(t, prefixes("10.0.0.0/32"), result)
+
+	_, err = RangeToPrefixes(netip.MustParseAddr("10.0.0.2"), netip.MustParseAddr("10.0.0.1"))
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	_, err = RangeToPrefixes(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1"))
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	_, err = RangeToPrefixes(netip.Addr{}, netip.MustParseAddr("::1"))
+	assert.ErrorIs(t, err, ErrInvalidPrefix)
+}
+
+func TestPrefixAddrs(t *testing.T) {
+	var addrs []string
+	for addr := range PrefixAddrs(netip.MustParsePrefix("10.0.0.5/30")) {
+		addrs = append(addrs, addr.String())
+	}
+
+	assert.Equal(t, []string{"10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"}, addrs)
+
+	count := 0
+	for range PrefixAddrs(netip.MustParsePrefix("255.255.255.252/30")) {
+		count++
+	}
+
+	assert.Equal(t, 4, count)
+
+	count = 0
+	for range PrefixAddrs(netip.MustParsePrefix("::/0")) {
+		if count++; count == 3 {
+			break
+		}
+	}
+
+	assert.Equal(t, 3, count)
+
+	for range PrefixAddrs(netip.Prefix{}) {
+		t.Fatal("invalid prefix must not yield")
+	}
+}
+
+func TestPrefixSize(t *testing.T) {
+	assert.Equal(t, big.NewInt(256), PrefixSize(netip.MustParsePrefix("10.0.0.0/24")))
+	assert.Equal(t, big.NewInt(1), PrefixSize(netip.MustParsePrefix("::1/128")))
+	assert.Equal(t, "79228162514264337593543950336", PrefixSize(netip.MustParsePrefix("2001:db8::/32")).String())
+	assert.Equal(t, "340282366920938463463374607431768211456", PrefixSize(netip.MustParsePrefix("::/0")).String())
+	assert.Equal(t, big.NewInt(0), PrefixSize(netip.Prefix{}))
+
+	assert.Equal(t, big.NewInt(384), PrefixesSize(prefixes("10.0.0.0/24", "10.0.0.0/25", "10.0.1.0/25")))
+
+	first, last := PrefixRange(netip.MustParsePrefix("10.1.2.3/20"))
+	assert.Equal(t, netip.MustParseAddr("10.1.0.0"), first)
+	assert.Equal(t, netip.MustParseAddr("10.1.15.255"), last)
+}
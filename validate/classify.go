@@ -0,0 +1,330 @@
+package validate
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The registries are copies of the IANA IPv4 and IPv6 Special-Purpose Address
+// Registries in their published CSV form, with footnote markers removed.
+// https://www.iana.org/assignments/iana-ipv4-special-registry/
+// https://www.iana.org/assignments/iana-ipv6-special-registry/
+var (
+	//go:embed iana/iana-ipv4-special-registry.csv
+	ianaIPv4SpecialRegistry []byte
+
+	//go:embed iana/iana-ipv6-special-registry.csv
+	ianaIPv6SpecialRegistry []byte
+)
+
+// IPCategory classifies an IP address by the special-purpose block it falls in.
+type IPCategory int
+
+const (
+	// CategoryInvalid is returned for nil or malformed addresses.
+	CategoryInvalid IPCategory = iota
+	// CategoryGlobalUnicast is ordinary, globally routable unicast space.
+	CategoryGlobalUnicast
+	// CategoryUnspecified is 0.0.0.0/32 and ::/128.
+	CategoryUnspecified
+	// CategoryThisNetwork is 0.0.0.0/8 ("this network").
+	CategoryThisNetwork
+	// CategoryLoopback is 127.0.0.0/8 and ::1/128.
+	CategoryLoopback
+	// CategoryPrivate is RFC 1918 space and IPv6 unique-local addresses (fc00::/7).
+	CategoryPrivate
+	// CategoryCGNAT is the RFC 6598 shared address space 100.64.0.0/10.
+	CategoryCGNAT
+	// CategoryLinkLocal is 169.254.0.0/16 and fe80::/10.
+	CategoryLinkLocal
+	// CategoryDocumentation is TEST-NET-1/2/3, 2001:db8::/32 and 3fff::/20.
+	CategoryDocumentation
+	// CategoryBenchmarking is 198.18.0.0/15 and 2001:2::/48.
+	CategoryBenchmarking
+	// CategoryMulticast is 224.0.0.0/4 and ff00::/8.
+	CategoryMulticast
+	// CategoryBroadcast is the limited broadcast address 255.255.255.255.
+	CategoryBroadcast
+	// CategoryReserved is space reserved for future use, such as 240.0.0.0/4
+	// and IPv6 outside 2000::/3 that has no other assignment.
+	CategoryReserved
+	// CategoryProtocolAssignment is IETF protocol space (192.0.0.0/24, 2001::/23)
+	// and the more specific protocol blocks inside it, such as well-known anycast
+	// addresses, AS112 and AMT.
+	CategoryProtocolAssignment
+	// CategoryIPv4Mapped is ::ffff:0:0/96.
+	CategoryIPv4Mapped
+	// CategoryTranslation is the NAT64 prefixes 64:ff9b::/96 and 64:ff9b:1::/48.
+	CategoryTranslation
+	// CategoryDiscard is the discard-only prefix 100::/64.
+	CategoryDiscard
+	// Category6to4 is 2002::/16.
+	Category6to4
+	// CategoryTeredo is 2001::/32.
+	CategoryTeredo
+	// CategoryDeprecated is a block whose special-purpose assignment has been
+	// terminated, such as the 6to4 relay anycast prefix 192.88.99.0/24.
+	CategoryDeprecated
+)
+
+var ipCategoryNames = [...]string{
+	CategoryInvalid:            "invalid",
+	CategoryGlobalUnicast:      "global-unicast",
+	CategoryUnspecified:        "unspecified",
+	CategoryThisNetwork:        "this-network",
+	CategoryLoopback:           "loopback",
+	CategoryPrivate:            "private",
+	CategoryCGNAT:              "cgnat",
+	CategoryLinkLocal:          "link-local",
+	CategoryDocumentation:      "documentation",
+	CategoryBenchmarking:       "benchmarking",
+	CategoryMulticast:          "multicast",
+	CategoryBroadcast:          "broadcast",
+	CategoryReserved:           "reserved",
+	CategoryProtocolAssignment: "protocol-assignment",
+	CategoryIPv4Mapped:         "ipv4-mapped",
+	CategoryTranslation:        "translation",
+	CategoryDiscard:            "discard",
+	Category6to4:               "6to4",
+	CategoryTeredo:             "teredo",
+	CategoryDeprecated:         "deprecated",
+}
+
+// String returns a short lower-case name for the category, such as "cgnat".
+func (c IPCategory) String() string {
+	if c >= 0 && int(c) < len(ipCategoryNames) {
+		return ipCategoryNames[c]
+	}
+
+	return "IPCategory(" + strconv.Itoa(int(c)) + ")"
+}
+
+// registryCategories maps registry address blocks to their category. Blocks
+// not listed here are protocol assignments.
+var registryCategories = map[string]IPCategory{
+	"0.0.0.0/8":          CategoryThisNetwork,
+	"0.0.0.0/32":         CategoryUnspecified,
+	"10.0.0.0/8":         CategoryPrivate,
+	"100.64.0.0/10":      CategoryCGNAT,
+	"127.0.0.0/8":        CategoryLoopback,
+	"169.254.0.0/16":     CategoryLinkLocal,
+	"172.16.0.0/12":      CategoryPrivate,
+	"192.0.2.0/24":       CategoryDocumentation,
+	"192.88.99.0/24":     CategoryDeprecated,
+	"192.168.0.0/16":     CategoryPrivate,
+	"198.18.0.0/15":      CategoryBenchmarking,
+	"198.51.100.0/24":    CategoryDocumentation,
+	"203.0.113.0/24":     CategoryDocumentation,
+	"240.0.0.0/4":        CategoryReserved,
+	"255.255.255.255/32": CategoryBroadcast,
+	"::/128":             CategoryUnspecified,
+	"::1/128":            CategoryLoopback,
+	"::ffff:0:0/96":      CategoryIPv4Mapped,
+	"64:ff9b::/96":       CategoryTranslation,
+	"64:ff9b:1::/48":     CategoryTranslation,
+	"100::/64":           CategoryDiscard,
+	"2001::/32":          CategoryTeredo,
+	"2001:2::/48":        CategoryBenchmarking,
+	"2001:10::/28":       CategoryDeprecated,
+	"2001:db8::/32":      CategoryDocumentation,
+	"2002::/16":          Category6to4,
+	"3fff::/20":          CategoryDocumentation,
+	"fc00::/7":           CategoryPrivate,
+	"fe80::/10":          CategoryLinkLocal,
+}
+
+// RegistryFlag is a boolean column of the special-purpose registries, which
+// may also be "N/A" when the answer depends on the address itself.
+type RegistryFlag int8
+
+const (
+	// FlagNA means the registry marks the property as not applicable.
+	FlagNA RegistryFlag = iota
+	// FlagFalse means the property does not hold.
+	FlagFalse
+	// FlagTrue means the property holds.
+	FlagTrue
+)
+
+// Bool reports whether the flag is FlagTrue.
+func (f RegistryFlag) Bool() bool {
+	return f == FlagTrue
+}
+
+// String returns "True", "False" or "N/A" as written in the registry.
+func (f RegistryFlag) String() string {
+	switch f {
+	case FlagTrue:
+		return "True"
+	case FlagFalse:
+		return "False"
+	default:
+		return "N/A"
+	}
+}
+
+// SpecialPurposeEntry is a row of the IANA special-purpose address registries.
+type SpecialPurposeEntry struct {
+	Prefix             netip.Prefix
+	Name               string
+	RFC                string
+	Category           IPCategory
+	Source             RegistryFlag
+	Destination        RegistryFlag
+	Forwardable        RegistryFlag
+	GloballyReachable  RegistryFlag
+	ReservedByProtocol RegistryFlag
+
+	// Terminated is set for blocks whose assignment has ended; all flags are N/A.
+	Terminated bool
+}
+
+// SpecialPurposeRegistry holds the parsed registry rows, IPv4 first, each
+// family ordered by address and then by prefix length.
+var SpecialPurposeRegistry = loadSpecialPurposeRegistry()
+
+func loadSpecialPurposeRegistry() []SpecialPurposeEntry {
+	entries := append(parseSpecialPurposeRegistry(ianaIPv4SpecialRegistry), parseSpecialPurposeRegistry(ianaIPv6SpecialRegistry)...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Prefix, entries[j].Prefix
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+
+		return a.Bits() < b.Bits()
+	})
+
+	return entries
+}
+
+func parseSpecialPurposeRegistry(data []byte) []SpecialPurposeEntry {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		panic("validate: malformed special-purpose registry: " + err.Error())
+	}
+
+	var entries []SpecialPurposeEntry
+	for _, record := range records[1:] {
+		entry := SpecialPurposeEntry{
+			Name:               record[1],
+			RFC:                record[2],
+			Source:             parseRegistryFlag(record[5]),
+			Destination:        parseRegistryFlag(record[6]),
+			Forwardable:        parseRegistryFlag(record[7]),
+			GloballyReachable:  parseRegistryFlag(record[8]),
+			ReservedByProtocol: parseRegistryFlag(record[9]),
+			Terminated:         record[4] != "" && record[4] != "N/A",
+		}
+
+		// A single row may list several blocks separated by commas.
+		for _, block := range strings.Split(record[0], ",") {
+			block = strings.TrimSpace(block)
+			entry.Prefix = netip.MustParsePrefix(block)
+			entry.Category = CategoryProtocolAssignment
+			if category, found := registryCategories[block]; found {
+				entry.Category = category
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+func parseRegistryFlag(value string) RegistryFlag {
+	// Tolerate footnote markers such as "False [1]" from the upstream file.
+	if i := strings.IndexByte(value, '['); i >= 0 {
+		value = value[:i]
+	}
+
+	switch strings.TrimSpace(value) {
+	case "True":
+		return FlagTrue
+	case "False":
+		return FlagFalse
+	default:
+		return FlagNA
+	}
+}
+
+var (
+	ipv4Multicast     = netip.MustParsePrefix("224.0.0.0/4")
+	ipv6Multicast     = netip.MustParsePrefix("ff00::/8")
+	ipv6GlobalUnicast = netip.MustParsePrefix("2000::/3")
+)
+
+// LookupSpecialPurpose returns the most specific registry entry containing ip.
+func LookupSpecialPurpose(ip net.IP) (SpecialPurposeEntry, bool) {
+	addr, ok := addrFromIP(ip)
+	if !ok {
+		return SpecialPurposeEntry{}, false
+	}
+
+	return LookupSpecialPurposeAddr(addr)
+}
+
+// LookupSpecialPurposeAddr is LookupSpecialPurpose for netip.Addr. Unlike the
+// net.IP form, an IPv4-mapped IPv6 address is matched against ::ffff:0:0/96.
+func LookupSpecialPurposeAddr(addr netip.Addr) (SpecialPurposeEntry, bool) {
+	best := -1
+	addr = addr.WithZone("")
+	for i := range SpecialPurposeRegistry {
+		prefix := SpecialPurposeRegistry[i].Prefix
+		if prefix.Contains(addr) && (best < 0 || prefix.Bits() > SpecialPurposeRegistry[best].Prefix.Bits()) {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		return SpecialPurposeEntry{}, false
+	}
+
+	return SpecialPurposeRegistry[best], true
+}
+
+// Classify returns the category of ip. A 4-byte or IPv4-mapped net.IP is
+// classified as IPv4.
+func Classify(ip net.IP) IPCategory {
+	addr, ok := addrFromIP(ip)
+	if !ok {
+		return CategoryInvalid
+	}
+
+	return ClassifyAddr(addr)
+}
+
+// ClassifyAddr returns the category of addr.
+func ClassifyAddr(addr netip.Addr) IPCategory {
+	if !addr.IsValid() {
+		return CategoryInvalid
+	}
+
+	if entry, found := LookupSpecialPurposeAddr(addr); found {
+		return entry.Category
+	}
+
+	switch {
+	case addr.Is4() && ipv4Multicast.Contains(addr):
+		return CategoryMulticast
+	case addr.Is6() && ipv6Multicast.Contains(addr.WithZone("")):
+		return CategoryMulticast
+	case addr.Is6() && !ipv6GlobalUnicast.Contains(addr.WithZone("")):
+		return CategoryReserved
+	default:
+		return CategoryGlobalUnicast
+	}
+}
+
+// addrFromIP converts a net.IP to a netip.Addr, unmapping IPv4 addresses that
+// net.ParseIP stores in 16-byte form.
+func addrFromIP(ip net.IP) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	return addr.Unmap(), ok
+}
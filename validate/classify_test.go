@@ -0,0 +1,124 @@
+package validate
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	cases := map[string]IPCategory{
+		"8.8.8.8":            CategoryGlobalUnicast,
+		"192.18.0.1":         CategoryGlobalUnicast,
+		"0.0.0.0":            CategoryUnspecified,
+		"0.1.2.3":            CategoryThisNetwork,
+		"127.0.0.1":          CategoryLoopback,
+		"10.24.2.62":         CategoryPrivate,
+		"172.31.255.255":     CategoryPrivate,
+		"192.168.1.1":        CategoryPrivate,
+		"100.64.0.1":         CategoryCGNAT,
+		"169.254.1.1":        CategoryLinkLocal,
+		"192.0.2.1":          CategoryDocumentation,
+		"198.51.100.7":       CategoryDocumentation,
+		"203.0.113.200":      CategoryDocumentation,
+		"198.18.0.1":         CategoryBenchmarking,
+		"198.19.255.255":     CategoryBenchmarking,
+		"224.0.0.251":        CategoryMulticast,
+		"255.255.255.255":    CategoryBroadcast,
+		"240.0.0.1":          CategoryReserved,
+		"192.0.0.9":          CategoryProtocolAssignment,
+		"192.0.0.200":        CategoryProtocolAssignment,
+		"192.88.99.1":        CategoryDeprecated,
+		"2606:4700::1111":    CategoryGlobalUnicast,
+		"::":                 CategoryUnspecified,
+		"::1":                CategoryLoopback,
+		"fd00::1":            CategoryPrivate,
+		"fe80::1":            CategoryLinkLocal,
+		"2001:db8::1":        CategoryDocumentation,
+		"3fff::1":            CategoryDocumentation,
+		"2001:2::1":          CategoryBenchmarking,
+		"ff02::1":            CategoryMulticast,
+		"2002:c000:204::1":   Category6to4,
+		"2001:0:4136:e378::": CategoryTeredo,
+		"64:ff9b::1.2.3.4":   CategoryTranslation,
+		"100::1":             CategoryDiscard,
+		"2001:1::1":          CategoryProtocolAssignment,
+		"4000::1":            CategoryReserved,
+		"::ffff:10.0.0.1":    CategoryPrivate,
+	}
+
+	for in, expected := range cases {
+		assert.Equal(t, expected, Classify(net.ParseIP(in)), in)
+	}
+
+	assert.Equal(t, CategoryInvalid, Classify(nil))
+	assert.Equal(t, CategoryInvalid, Classify(net.IP{1, 2, 3}))
+	assert.Equal(t, CategoryInvalid, ClassifyAddr(netip.Addr{}))
+	assert.Equal(t, CategoryPrivate, Classify(net.IP{10, 0, 0, 1}))
+}
+
+func TestClassifyAddr(t *testing.T) {
+	// netip keeps IPv4-mapped addresses distinct
+	assert.Equal(t, CategoryIPv4Mapped, ClassifyAddr(netip.MustParseAddr("::ffff:10.0.0.1")))
+	assert.Equal(t, CategoryLinkLocal, ClassifyAddr(netip.MustParseAddr("fe80::1%eth0")))
+	assert.Equal(t, CategoryMulticast, ClassifyAddr(netip.MustParseAddr("ff02::1%eth0")))
+	assert.Equal(t, "cgnat", CategoryCGNAT.String())
+	assert.Equal(t, "IPCategory(99)", IPCategory(99).String())
+}
+
+func TestLookupSpecialPurpose(t *testing.T) {
+	entry, found := LookupSpecialPurpose(net.ParseIP("192.0.0.170"))
+	assert.True(t, found)
+	assert.Equal(t, "NAT64/DNS64 Discovery", entry.Name)
+	assert.Equal(t, netip.MustParsePrefix("192.0.0.170/32"), entry.Prefix)
+	assert.Equal(t, FlagTrue, entry.ReservedByProtocol)
+
+	entry, found = LookupSpecialPurpose(net.ParseIP("2001:4:112::1"))
+	assert.True(t, found)
+	assert.Equal(t, "AS112-v6", entry.Name)
+	assert.True(t, entry.GloballyReachable.Bool())
+	assert.True(t, entry.Forwardable.Bool())
+
+	entry, found = LookupSpecialPurpose(net.ParseIP("2001::1"))
+	assert.True(t, found)
+	assert.Equal(t, "TEREDO", entry.Name)
+	assert.Equal(t, FlagNA, entry.GloballyReachable)
+	assert.Equal(t, "N/A", entry.GloballyReachable.String())
+
+	entry, found = LookupSpecialPurpose(net.ParseIP("169.254.0.1"))
+	assert.True(t, found)
+	assert.False(t, entry.Forwardable.Bool())
+	assert.Equal(t, FlagFalse, entry.GloballyReachable)
+
+	entry, found = LookupSpecialPurpose(net.ParseIP("192.88.99.1"))
+	assert.True(t, found)
+	assert.True(t, entry.Terminated)
+
+	_, found = LookupSpecialPurpose(net.ParseIP("8.8.8.8"))
+	assert.False(t, found)
+	_, found = LookupSpecialPurpose(nil)
+	assert.False(t, found)
+}
+
+func TestSpecialPurposeRegistry(t *testing.T) {
+	assert.NotEmpty(t, SpecialPurposeRegistry)
+	assert.True(t, SpecialPurposeRegistry[0].Prefix.Addr().Is4())
+	assert.True(t, SpecialPurposeRegistry[len(SpecialPurposeRegistry)-1].Prefix.Addr().Is6())
+
+	for _, entry := range SpecialPurposeRegistry {
+		assert.True(t, entry.Prefix.IsValid(), entry.Name)
+		assert.Equal(t, entry.Prefix.Masked(), entry.Prefix, entry.Name)
+		assert.NotEmpty(t, entry.Name)
+	}
+
+	for block := range registryCategories {
+		found := false
+		for _, entry := range SpecialPurposeRegistry {
+			found = found || entry.Prefix.String() == netip.MustParsePrefix(block).String()
+		}
+
+		assert.True(t, found, block)
+	}
+}
@@ -2,11 +2,15 @@
 // IP addresses, phone numbers, email addresses, and other common formats.
 package validate
 
-import "net"
+import (
+	"net"
+	"sync/atomic"
+)
 
 // NonPublicIPNet contains a list of IP network ranges that are considered non-public.
 // This includes private networks, loopback addresses, multicast ranges, and other
-// special-use IP address ranges as defined by various RFCs.
+// special-use IP address ranges as defined by various RFCs. Call
+// RebuildNonPublicIPSet after changing it.
 var NonPublicIPNet = []net.IPNet{
 	*ParseIPNet("10.0.0.0/8"),
 	*ParseIPNet("172.16.0.0/12"),
@@ -19,7 +23,7 @@ var NonPublicIPNet = []net.IPNet{
 	*ParseIPNet("198.51.100.0/24"),
 	*ParseIPNet("203.0.113.0/24"),
 	*ParseIPNet("192.88.99.0/24"),
-	*ParseIPNet("192.18.0.0/15"),
+	*ParseIPNet("198.18.0.0/15"),
 	*ParseIPNet("224.0.0.0/4"),
 	*ParseIPNet("240.0.0.0/4"),
 	*ParseIPNet("255.255.255.255/32"),
@@ -30,7 +34,6 @@ var NonPublicIPNet = []net.IPNet{
 	*ParseIPNet("2001::/23"),
 	*ParseIPNet("2001:2::/48"),
 	*ParseIPNet("2001:db8::/32"),
-	*ParseIPNet("2001::/32"),
 	*ParseIPNet("fc00::/7"),
 	*ParseIPNet("fe80::/10"),
 	*ParseIPNet("ff00::/8"),
@@ -45,14 +48,33 @@ func ParseIPNet(cidr string) *net.IPNet {
 	}
 }
 
-func IsPublicIP(ip net.IP) bool {
-	for _, ipNet := range NonPublicIPNet {
-		if ipNet.Contains(ip) {
-			return false
-		}
+// nonPublicIPSet caches an IPSet built from NonPublicIPNet on first use.
+var nonPublicIPSet atomic.Pointer[IPSet]
+
+// NonPublicIPSet returns an IPSet holding the networks of NonPublicIPNet.
+// The set is built on first use; call RebuildNonPublicIPSet after changing
+// NonPublicIPNet. The returned set is shared and must not be modified.
+func NonPublicIPSet() *IPSet {
+	if set := nonPublicIPSet.Load(); set != nil {
+		return set
 	}
 
-	return true
+	set := NewIPSet(NonPublicIPNet...)
+	if nonPublicIPSet.CompareAndSwap(nil, set) {
+		return set
+	}
+
+	return nonPublicIPSet.Load()
+}
+
+// RebuildNonPublicIPSet rebuilds the set returned by NonPublicIPSet, and used
+// by IsPublicIP, from the current NonPublicIPNet.
+func RebuildNonPublicIPSet() {
+	nonPublicIPSet.Store(NewIPSet(NonPublicIPNet...))
+}
+
+func IsPublicIP(ip net.IP) bool {
+	return !NonPublicIPSet().Contains(ip)
 }
 
 func IsCIDRContain(cidr string, ip net.IP) bool {
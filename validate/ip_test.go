@@ -10,4 +10,7 @@ import (
 func TestIsPublicIP(t *testing.T) {
 	assert.False(t, IsPublicIP(net.ParseIP("10.24.2.62")))
 	assert.True(t, IsPublicIP(net.ParseIP("110.24.2.62")))
+	assert.False(t, IsPublicIP(net.ParseIP("198.18.0.1")))
+	assert.True(t, IsPublicIP(net.ParseIP("192.18.0.1")))
+	assert.False(t, IsPublicIP(net.ParseIP("2001::1")))
 }
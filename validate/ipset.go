@@ -0,0 +1,383 @@
+package validate
+
+import (
+	"math/bits"
+	"net"
+	"net/netip"
+	"sync"
+)
+
+// IPSet is a set of IP prefixes backed by a compressed binary prefix trie
+// (one for IPv4 and one for IPv6), with an optional value attached to every
+// prefix. Membership and longest-prefix lookups cost at most one node visit
+// per prefix bit regardless of how many prefixes the set holds.
+//
+// IPSet is safe for concurrent use; lookups run in parallel with each other
+// and are only serialised against modifications.
+//
+// IPv4 addresses and IPv4-mapped IPv6 addresses are treated as the same
+// address, matching the behaviour of net.IPNet.Contains.
+type IPSet struct {
+	mu   sync.RWMutex
+	v4   *ipNode
+	v6   *ipNode
+	size int
+}
+
+// ipKey holds up to 128 address bits, most significant bit first. IPv4
+// addresses occupy the top 32 bits of the first word.
+type ipKey [2]uint64
+
+type ipNode struct {
+	key      ipKey
+	bits     int
+	children [2]*ipNode
+	value    interface{}
+	set      bool
+}
+
+// NewIPSet creates a set holding the given networks with nil values.
+func NewIPSet(nets ...net.IPNet) *IPSet {
+	s := &IPSet{}
+	for i := range nets {
+		s.Add(&nets[i])
+	}
+
+	return s
+}
+
+// Add inserts ipNet into the set. Nil or malformed networks are ignored.
+func (s *IPSet) Add(ipNet *net.IPNet) {
+	if prefix, ok := prefixFromIPNet(ipNet); ok {
+		s.AddPrefix(prefix, nil)
+	}
+}
+
+// AddCIDR parses cidr and inserts it into the set, reporting whether it was valid.
+func (s *IPSet) AddCIDR(cidr string) bool {
+	ipNet := ParseIPNet(cidr)
+	if ipNet == nil {
+		return false
+	}
+
+	s.Add(ipNet)
+	return true
+}
+
+// AddPrefix inserts prefix with an attached value, replacing the value if the
+// prefix is already present. Host bits of prefix are ignored.
+func (s *IPSet) AddPrefix(prefix netip.Prefix, value interface{}) {
+	if !prefix.IsValid() {
+		return
+	}
+
+	prefix = normalizePrefix(prefix)
+	key := keyOf(prefix.Addr())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.root(prefix.Addr())
+	for {
+		cur := *n
+		if cur == nil {
+			*n = &ipNode{key: key, bits: prefix.Bits(), value: value, set: true}
+			s.size++
+			return
+		}
+
+		common := commonPrefixLen(cur.key, key, min(cur.bits, prefix.Bits()))
+		switch {
+		case common == cur.bits && common == prefix.Bits():
+			if !cur.set {
+				s.size++
+			}
+
+			cur.value, cur.set = value, true
+			return
+		case common == cur.bits:
+			n = &cur.children[key.bit(cur.bits)]
+		case common == prefix.Bits():
+			leaf := &ipNode{key: key, bits: prefix.Bits(), value: value, set: true}
+			leaf.children[cur.key.bit(common)] = cur
+			*n = leaf
+			s.size++
+			return
+		default:
+			glue := &ipNode{key: key.mask(common), bits: common}
+			glue.children[key.bit(common)] = &ipNode{key: key, bits: prefix.Bits(), value: value, set: true}
+			glue.children[cur.key.bit(common)] = cur
+			*n = glue
+			s.size++
+			return
+		}
+	}
+}
+
+// Remove deletes ipNet from the set, reporting whether it was present.
+// Only the exact prefix is removed; covering and covered prefixes stay.
+func (s *IPSet) Remove(ipNet *net.IPNet) bool {
+	if prefix, ok := prefixFromIPNet(ipNet); ok {
+		return s.RemovePrefix(prefix)
+	}
+
+	return false
+}
+
+// RemovePrefix deletes prefix from the set, reporting whether it was present.
+func (s *IPSet) RemovePrefix(prefix netip.Prefix) bool {
+	if !prefix.IsValid() {
+		return false
+	}
+
+	prefix = normalizePrefix(prefix)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if removeNode(s.root(prefix.Addr()), keyOf(prefix.Addr()), prefix.Bits()) {
+		s.size--
+		return true
+	}
+
+	return false
+}
+
+// removeNode unsets the node for key/bits below n and prunes nodes that no
+// longer carry a value or branch.
+func removeNode(n **ipNode, key ipKey, prefixBits int) bool {
+	cur := *n
+	if cur == nil || cur.bits > prefixBits || commonPrefixLen(cur.key, key, cur.bits) < cur.bits {
+		return false
+	}
+
+	removed := false
+	if cur.bits == prefixBits {
+		if !cur.set {
+			return false
+		}
+
+		cur.value, cur.set, removed = nil, false, true
+	} else {
+		removed = removeNode(&cur.children[key.bit(cur.bits)], key, prefixBits)
+	}
+
+	if removed && !cur.set {
+		switch {
+		case cur.children[0] == nil:
+			*n = cur.children[1]
+		case cur.children[1] == nil:
+			*n = cur.children[0]
+		}
+	}
+
+	return removed
+}
+
+// Contains reports whether ip falls inside any prefix of the set.
+func (s *IPSet) Contains(ip net.IP) bool {
+	addr, ok := addrFromIP(ip)
+	return ok && s.ContainsAddr(addr)
+}
+
+// ContainsAddr reports whether addr falls inside any prefix of the set.
+func (s *IPSet) ContainsAddr(addr netip.Addr) bool {
+	_, _, found := s.LookupAddr(addr)
+	return found
+}
+
+// Lookup returns the longest prefix containing ip together with its value.
+func (s *IPSet) Lookup(ip net.IP) (*net.IPNet, interface{}, bool) {
+	addr, ok := addrFromIP(ip)
+	if !ok {
+		return nil, nil, false
+	}
+
+	prefix, value, found := s.LookupAddr(addr)
+	if !found {
+		return nil, nil, false
+	}
+
+	return ipNetFromPrefix(prefix), value, true
+}
+
+// LookupAddr returns the longest prefix containing addr together with its value.
+func (s *IPSet) LookupAddr(addr netip.Addr) (netip.Prefix, interface{}, bool) {
+	if !addr.IsValid() {
+		return netip.Prefix{}, nil, false
+	}
+
+	addr = addr.Unmap().WithZone("")
+	key := keyOf(addr)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var best *ipNode
+	for cur := *s.root(addr); cur != nil; {
+		if commonPrefixLen(cur.key, key, cur.bits) < cur.bits {
+			break
+		}
+
+		if cur.set {
+			best = cur
+		}
+
+		if cur.bits == addr.BitLen() {
+			break
+		}
+
+		cur = cur.children[key.bit(cur.bits)]
+	}
+
+	if best == nil {
+		return netip.Prefix{}, nil, false
+	}
+
+	return prefixOf(best.key, best.bits, addr.Is4()), best.value, true
+}
+
+// Len returns the number of prefixes in the set.
+func (s *IPSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size
+}
+
+// Prefixes returns the prefixes of the set, IPv4 first, in address order with
+// covering prefixes before the prefixes they cover.
+func (s *IPSet) Prefixes() []netip.Prefix {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefixes := make([]netip.Prefix, 0, s.size)
+	var walk func(n *ipNode, is4 bool)
+	walk = func(n *ipNode, is4 bool) {
+		if n == nil {
+			return
+		}
+
+		if n.set {
+			prefixes = append(prefixes, prefixOf(n.key, n.bits, is4))
+		}
+
+		walk(n.children[0], is4)
+		walk(n.children[1], is4)
+	}
+
+	walk(s.v4, true)
+	walk(s.v6, false)
+	return prefixes
+}
+
+func (s *IPSet) root(addr netip.Addr) **ipNode {
+	if addr.Is4() {
+		return &s.v4
+	}
+
+	return &s.v6
+}
+
+func keyOf(addr netip.Addr) ipKey {
+	if addr.Is4() {
+		b := addr.As4()
+		return ipKey{uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32, 0}
+	}
+
+	b := addr.As16()
+	var k ipKey
+	for i := 0; i < 16; i++ {
+		k[i/8] |= uint64(b[i]) << (56 - 8*(i%8))
+	}
+
+	return k
+}
+
+func prefixOf(k ipKey, prefixBits int, is4 bool) netip.Prefix {
+	if is4 {
+		hi := k[0] >> 32
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(hi >> 24), byte(hi >> 16), byte(hi >> 8), byte(hi)}), prefixBits)
+	}
+
+	var b [16]byte
+	for i := 0; i < 16; i++ {
+		b[i] = byte(k[i/8] >> (56 - 8*(i%8)))
+	}
+
+	return netip.PrefixFrom(netip.AddrFrom16(b), prefixBits)
+}
+
+// bit returns bit i of the key, counting from the most significant bit.
+func (k ipKey) bit(i int) int {
+	return int(k[i/64]>>(63-i%64)) & 1
+}
+
+// mask clears every bit of the key from position n onwards.
+func (k ipKey) mask(n int) ipKey {
+	switch {
+	case n <= 0:
+		return ipKey{}
+	case n < 64:
+		return ipKey{k[0] &^ (^uint64(0) >> n), 0}
+	case n < 128:
+		return ipKey{k[0], k[1] &^ (^uint64(0) >> (n - 64))}
+	default:
+		return k
+	}
+}
+
+// commonPrefixLen returns the number of leading bits a and b share, capped at limit.
+func commonPrefixLen(a, b ipKey, limit int) int {
+	n := bits.LeadingZeros64(a[0] ^ b[0])
+	if n == 64 {
+		n += bits.LeadingZeros64(a[1] ^ b[1])
+	}
+
+	return min(n, limit)
+}
+
+// normalizePrefix unmaps IPv4-mapped prefixes of length 96 or more and clears host bits.
+func normalizePrefix(prefix netip.Prefix) netip.Prefix {
+	addr := prefix.Addr().WithZone("")
+	if addr.Is4In6() && prefix.Bits() >= 96 {
+		return netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96).Masked()
+	}
+
+	return netip.PrefixFrom(addr, prefix.Bits()).Masked()
+}
+
+// prefixFromIPNet converts a net.IPNet to a netip.Prefix.
+func prefixFromIPNet(ipNet *net.IPNet) (netip.Prefix, bool) {
+	if ipNet == nil {
+		return netip.Prefix{}, false
+	}
+
+	addr, ok := netip.AddrFromSlice(ipNet.IP)
+	ones, maskBits := ipNet.Mask.Size()
+	if !ok || maskBits == 0 {
+		return netip.Prefix{}, false
+	}
+
+	// A 4-byte mask on a 16-byte IPv4 address still describes an IPv4 network.
+	if maskBits == 32 {
+		if !addr.Unmap().Is4() {
+			return netip.Prefix{}, false
+		}
+
+		return netip.PrefixFrom(addr.Unmap(), ones).Masked(), true
+	}
+
+	if addr.Is4() {
+		addr = netip.AddrFrom16(addr.As16())
+	}
+
+	return normalizePrefix(netip.PrefixFrom(addr, ones)), true
+}
+
+// ipNetFromPrefix converts a netip.Prefix to a net.IPNet.
+func ipNetFromPrefix(prefix netip.Prefix) *net.IPNet {
+	return &net.IPNet{
+		IP:   net.IP(prefix.Addr().AsSlice()),
+		Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+	}
+}
@@ -0,0 +1,222 @@
+package validate
+
+import (
+	"math/rand"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPSetLookup(t *testing.T) {
+	s := &IPSet{}
+	s.AddPrefix(netip.MustParsePrefix("10.0.0.0/8"), "corp")
+	s.AddPrefix(netip.MustParsePrefix("10.1.0.0/16"), "office")
+	s.AddPrefix(netip.MustParsePrefix("10.1.2.3/32"), "host")
+	s.AddPrefix(netip.MustParsePrefix("2001:db8::/32"), "doc")
+	assert.Equal(t, 4, s.Len())
+
+	prefix, value, found := s.LookupAddr(netip.MustParseAddr("10.1.2.3"))
+	assert.True(t, found)
+	assert.Equal(t, netip.MustParsePrefix("10.1.2.3/32"), prefix)
+	assert.Equal(t, "host", value)
+
+	prefix, value, found = s.LookupAddr(netip.MustParseAddr("10.1.9.9"))
+	assert.True(t, found)
+	assert.Equal(t, netip.MustParsePrefix("10.1.0.0/16"), prefix)
+	assert.Equal(t, "office", value)
+
+	ipNet, value, found := s.Lookup(net.ParseIP("10.200.0.1"))
+	assert.True(t, found)
+	assert.Equal(t, "10.0.0.0/8", ipNet.String())
+	assert.Equal(t, "corp", value)
+
+	ipNet, value, found = s.Lookup(net.ParseIP("2001:db8:1::1"))
+	assert.True(t, found)
+	assert.Equal(t, "2001:db8::/32", ipNet.String())
+	assert.Equal(t, "doc", value)
+
+	_, _, found = s.Lookup(net.ParseIP("11.0.0.1"))
+	assert.False(t, found)
+	_, _, found = s.Lookup(nil)
+	assert.False(t, found)
+
+	// IPv4 and IPv4-mapped IPv6 addresses are the same address
+	assert.True(t, s.ContainsAddr(netip.MustParseAddr("::ffff:10.0.0.1")))
+	assert.False(t, s.ContainsAddr(netip.MustParseAddr("::a00:1")))
+
+	// Replacing a value keeps the size
+	s.AddPrefix(netip.MustParsePrefix("10.0.0.0/8"), "renamed")
+	assert.Equal(t, 4, s.Len())
+	_, value, _ = s.LookupAddr(netip.MustParseAddr("10.9.9.9"))
+	assert.Equal(t, "renamed", value)
+}
+
+func TestIPSetRemove(t *testing.T) {
+	s := NewIPSet(*ParseIPNet("10.0.0.0/8"), *ParseIPNet("10.1.0.0/16"), *ParseIPNet("10.2.0.0/16"))
+	assert.Equal(t, 3, s.Len())
+
+	assert.False(t, s.Remove(ParseIPNet("10.3.0.0/16")))
+	assert.False(t, s.Remove(ParseIPNet("10.0.0.0/9")))
+	assert.False(t, s.Remove(nil))
+
+	assert.True(t, s.Remove(ParseIPNet("10.0.0.0/8")))
+	assert.False(t, s.Remove(ParseIPNet("10.0.0.0/8")))
+	assert.Equal(t, 2, s.Len())
+	assert.False(t, s.Contains(net.ParseIP("10.3.0.1")))
+	assert.True(t, s.Contains(net.ParseIP("10.1.0.1")))
+	assert.True(t, s.Contains(net.ParseIP("10.2.0.1")))
+
+	assert.True(t, s.Remove(ParseIPNet("10.1.0.0/16")))
+	assert.True(t, s.Remove(ParseIPNet("10.2.0.0/16")))
+	assert.Equal(t, 0, s.Len())
+	assert.Nil(t, s.v4)
+}
+
+func TestIPSetAddCIDR(t *testing.T) {
+	s := &IPSet{}
+	assert.True(t, s.AddCIDR("192.168.1.77/24"))
+	assert.False(t, s.AddCIDR("not-a-cidr"))
+	assert.True(t, s.AddCIDR("::/0"))
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("192.168.1.0/24"),
+		netip.MustParsePrefix("::/0"),
+	}, s.Prefixes())
+
+	// A zero-length IPv4 prefix matches every IPv4 address
+	assert.True(t, s.AddCIDR("0.0.0.0/0"))
+	assert.True(t, s.Contains(net.ParseIP("8.8.8.8")))
+}
+
+func TestIPSetPrefixesOrder(t *testing.T) {
+	s := &IPSet{}
+	for _, cidr := range []string{"10.2.0.0/16", "fe80::/10", "10.0.0.0/8", "1.0.0.0/8", "::1/128"} {
+		s.AddCIDR(cidr)
+	}
+
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("1.0.0.0/8"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("10.2.0.0/16"),
+		netip.MustParsePrefix("::1/128"),
+		netip.MustParsePrefix("fe80::/10"),
+	}, s.Prefixes())
+}
+
+func TestIPSetMatchesLinearScan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	s := &IPSet{}
+	var prefixes []netip.Prefix
+	for i := 0; i < 2000; i++ {
+		var b [4]byte
+		rnd.Read(b[:])
+		p := netip.PrefixFrom(netip.AddrFrom4(b), 8+rnd.Intn(25)).Masked()
+		prefixes = append(prefixes, p)
+		s.AddPrefix(p, p)
+	}
+
+	// Remove a third of them again
+	live := map[netip.Prefix]bool{}
+	for _, p := range prefixes {
+		live[p] = true
+	}
+
+	for _, p := range prefixes[:len(prefixes)/3] {
+		if live[p] {
+			assert.True(t, s.RemovePrefix(p))
+			delete(live, p)
+		}
+	}
+
+	assert.Equal(t, len(live), s.Len())
+	for i := 0; i < 20000; i++ {
+		var b [4]byte
+		rnd.Read(b[:])
+		addr := netip.AddrFrom4(b)
+
+		var want netip.Prefix
+		for p := range live {
+			if p.Contains(addr) && (!want.IsValid() || p.Bits() > want.Bits()) {
+				want = p
+			}
+		}
+
+		got, value, found := s.LookupAddr(addr)
+		assert.Equal(t, want.IsValid(), found, addr.String())
+		if found {
+			assert.Equal(t, want, got, addr.String())
+			assert.Equal(t, want, value, addr.String())
+		}
+	}
+}
+
+func TestIPSetConcurrentReads(t *testing.T) {
+	s := NewIPSet(NonPublicIPNet...)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				assert.True(t, s.Contains(net.IPv4(10, byte(i), byte(j), 1)))
+				if i == 0 {
+					s.AddPrefix(netip.PrefixFrom(netip.AddrFrom4([4]byte{1, 2, byte(j), 0}), 24), j)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestNonPublicIPSet(t *testing.T) {
+	assert.Same(t, NonPublicIPSet(), NonPublicIPSet())
+	assert.Equal(t, len(NonPublicIPNet), NonPublicIPSet().Len())
+
+	saved := NonPublicIPNet
+	defer func() {
+		NonPublicIPNet = saved
+		RebuildNonPublicIPSet()
+	}()
+
+	// Changes take effect on rebuild only.
+	NonPublicIPNet = append(append([]net.IPNet{}, saved...), *ParseIPNet("110.24.0.0/16"))
+	assert.True(t, IsPublicIP(net.ParseIP("110.24.2.62")))
+	RebuildNonPublicIPSet()
+	assert.False(t, IsPublicIP(net.ParseIP("110.24.2.62")))
+
+	NonPublicIPNet[0] = *ParseIPNet("110.25.0.0/16")
+	RebuildNonPublicIPSet()
+	assert.False(t, IsPublicIP(net.ParseIP("110.25.2.62")))
+	assert.True(t, IsPublicIP(net.ParseIP("10.1.2.3")))
+
+	NonPublicIPNet = saved
+	RebuildNonPublicIPSet()
+	assert.True(t, IsPublicIP(net.ParseIP("110.24.2.62")))
+	assert.False(t, IsPublicIP(net.ParseIP("10.1.2.3")))
+}
+
+func BenchmarkIsPublicIP(b *testing.B) {
+	ip := net.ParseIP("110.24.2.62")
+	for i := 0; i < b.N; i++ {
+		IsPublicIP(ip)
+	}
+}
+
+func BenchmarkIPSetContains(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	s := &IPSet{}
+	for i := 0; i < 10000; i++ {
+		var b [4]byte
+		rnd.Read(b[:])
+		s.AddPrefix(netip.PrefixFrom(netip.AddrFrom4(b), 16+rnd.Intn(17)), nil)
+	}
+
+	addr := netip.MustParseAddr("110.24.2.62")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.ContainsAddr(addr)
+	}
+}